@@ -0,0 +1,329 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             v3.21.12
+// source: admin/v1/admin.proto
+
+package v1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// ProxyAdminServiceClient is the client API for ProxyAdminService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ProxyAdminServiceClient interface {
+	// ListE2TInstances lists the known E2T instances and the E2 nodes each one masters
+	ListE2TInstances(ctx context.Context, in *ListE2TInstancesRequest, opts ...grpc.CallOption) (*ListE2TInstancesResponse, error)
+	// ListE2Nodes lists the known E2 nodes and their masters
+	ListE2Nodes(ctx context.Context, in *ListE2NodesRequest, opts ...grpc.CallOption) (*ListE2NodesResponse, error)
+	// ListControlsRelations lists the known controls relations between E2T instances and E2 nodes
+	ListControlsRelations(ctx context.Context, in *ListControlsRelationsRequest, opts ...grpc.CallOption) (*ListControlsRelationsResponse, error)
+	// GetRoute returns the E2T instance requests targeting the given E2 node are presently routed to
+	GetRoute(ctx context.Context, in *GetRouteRequest, opts ...grpc.CallOption) (*GetRouteResponse, error)
+	// WatchRoutes streams changes in the routing of requests to E2 nodes
+	WatchRoutes(ctx context.Context, in *WatchRoutesRequest, opts ...grpc.CallOption) (ProxyAdminService_WatchRoutesClient, error)
+	// ListSubscriptions lists the subscriptions forwarded to E2T by the proxy which have not been unsubscribed
+	ListSubscriptions(ctx context.Context, in *ListSubscriptionsRequest, opts ...grpc.CallOption) (*ListSubscriptionsResponse, error)
+}
+
+type proxyAdminServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewProxyAdminServiceClient(cc grpc.ClientConnInterface) ProxyAdminServiceClient {
+	return &proxyAdminServiceClient{cc}
+}
+
+func (c *proxyAdminServiceClient) ListE2TInstances(ctx context.Context, in *ListE2TInstancesRequest, opts ...grpc.CallOption) (*ListE2TInstancesResponse, error) {
+	out := new(ListE2TInstancesResponse)
+	err := c.cc.Invoke(ctx, "/onos.proxy.admin.v1.ProxyAdminService/ListE2TInstances", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *proxyAdminServiceClient) ListE2Nodes(ctx context.Context, in *ListE2NodesRequest, opts ...grpc.CallOption) (*ListE2NodesResponse, error) {
+	out := new(ListE2NodesResponse)
+	err := c.cc.Invoke(ctx, "/onos.proxy.admin.v1.ProxyAdminService/ListE2Nodes", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *proxyAdminServiceClient) ListControlsRelations(ctx context.Context, in *ListControlsRelationsRequest, opts ...grpc.CallOption) (*ListControlsRelationsResponse, error) {
+	out := new(ListControlsRelationsResponse)
+	err := c.cc.Invoke(ctx, "/onos.proxy.admin.v1.ProxyAdminService/ListControlsRelations", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *proxyAdminServiceClient) GetRoute(ctx context.Context, in *GetRouteRequest, opts ...grpc.CallOption) (*GetRouteResponse, error) {
+	out := new(GetRouteResponse)
+	err := c.cc.Invoke(ctx, "/onos.proxy.admin.v1.ProxyAdminService/GetRoute", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *proxyAdminServiceClient) WatchRoutes(ctx context.Context, in *WatchRoutesRequest, opts ...grpc.CallOption) (ProxyAdminService_WatchRoutesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ProxyAdminService_ServiceDesc.Streams[0], "/onos.proxy.admin.v1.ProxyAdminService/WatchRoutes", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &proxyAdminServiceWatchRoutesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ProxyAdminService_WatchRoutesClient interface {
+	Recv() (*WatchRoutesResponse, error)
+	grpc.ClientStream
+}
+
+type proxyAdminServiceWatchRoutesClient struct {
+	grpc.ClientStream
+}
+
+func (x *proxyAdminServiceWatchRoutesClient) Recv() (*WatchRoutesResponse, error) {
+	m := new(WatchRoutesResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *proxyAdminServiceClient) ListSubscriptions(ctx context.Context, in *ListSubscriptionsRequest, opts ...grpc.CallOption) (*ListSubscriptionsResponse, error) {
+	out := new(ListSubscriptionsResponse)
+	err := c.cc.Invoke(ctx, "/onos.proxy.admin.v1.ProxyAdminService/ListSubscriptions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ProxyAdminServiceServer is the server API for ProxyAdminService service.
+// All implementations must embed UnimplementedProxyAdminServiceServer
+// for forward compatibility
+type ProxyAdminServiceServer interface {
+	// ListE2TInstances lists the known E2T instances and the E2 nodes each one masters
+	ListE2TInstances(context.Context, *ListE2TInstancesRequest) (*ListE2TInstancesResponse, error)
+	// ListE2Nodes lists the known E2 nodes and their masters
+	ListE2Nodes(context.Context, *ListE2NodesRequest) (*ListE2NodesResponse, error)
+	// ListControlsRelations lists the known controls relations between E2T instances and E2 nodes
+	ListControlsRelations(context.Context, *ListControlsRelationsRequest) (*ListControlsRelationsResponse, error)
+	// GetRoute returns the E2T instance requests targeting the given E2 node are presently routed to
+	GetRoute(context.Context, *GetRouteRequest) (*GetRouteResponse, error)
+	// WatchRoutes streams changes in the routing of requests to E2 nodes
+	WatchRoutes(*WatchRoutesRequest, ProxyAdminService_WatchRoutesServer) error
+	// ListSubscriptions lists the subscriptions forwarded to E2T by the proxy which have not been unsubscribed
+	ListSubscriptions(context.Context, *ListSubscriptionsRequest) (*ListSubscriptionsResponse, error)
+	mustEmbedUnimplementedProxyAdminServiceServer()
+}
+
+// UnimplementedProxyAdminServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedProxyAdminServiceServer struct {
+}
+
+func (UnimplementedProxyAdminServiceServer) ListE2TInstances(context.Context, *ListE2TInstancesRequest) (*ListE2TInstancesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListE2TInstances not implemented")
+}
+func (UnimplementedProxyAdminServiceServer) ListE2Nodes(context.Context, *ListE2NodesRequest) (*ListE2NodesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListE2Nodes not implemented")
+}
+func (UnimplementedProxyAdminServiceServer) ListControlsRelations(context.Context, *ListControlsRelationsRequest) (*ListControlsRelationsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListControlsRelations not implemented")
+}
+func (UnimplementedProxyAdminServiceServer) GetRoute(context.Context, *GetRouteRequest) (*GetRouteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRoute not implemented")
+}
+func (UnimplementedProxyAdminServiceServer) WatchRoutes(*WatchRoutesRequest, ProxyAdminService_WatchRoutesServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchRoutes not implemented")
+}
+func (UnimplementedProxyAdminServiceServer) ListSubscriptions(context.Context, *ListSubscriptionsRequest) (*ListSubscriptionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSubscriptions not implemented")
+}
+func (UnimplementedProxyAdminServiceServer) mustEmbedUnimplementedProxyAdminServiceServer() {}
+
+// UnsafeProxyAdminServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ProxyAdminServiceServer will
+// result in compilation errors.
+type UnsafeProxyAdminServiceServer interface {
+	mustEmbedUnimplementedProxyAdminServiceServer()
+}
+
+func RegisterProxyAdminServiceServer(s grpc.ServiceRegistrar, srv ProxyAdminServiceServer) {
+	s.RegisterService(&ProxyAdminService_ServiceDesc, srv)
+}
+
+func _ProxyAdminService_ListE2TInstances_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListE2TInstancesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProxyAdminServiceServer).ListE2TInstances(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/onos.proxy.admin.v1.ProxyAdminService/ListE2TInstances",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProxyAdminServiceServer).ListE2TInstances(ctx, req.(*ListE2TInstancesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProxyAdminService_ListE2Nodes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListE2NodesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProxyAdminServiceServer).ListE2Nodes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/onos.proxy.admin.v1.ProxyAdminService/ListE2Nodes",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProxyAdminServiceServer).ListE2Nodes(ctx, req.(*ListE2NodesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProxyAdminService_ListControlsRelations_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListControlsRelationsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProxyAdminServiceServer).ListControlsRelations(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/onos.proxy.admin.v1.ProxyAdminService/ListControlsRelations",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProxyAdminServiceServer).ListControlsRelations(ctx, req.(*ListControlsRelationsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProxyAdminService_GetRoute_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRouteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProxyAdminServiceServer).GetRoute(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/onos.proxy.admin.v1.ProxyAdminService/GetRoute",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProxyAdminServiceServer).GetRoute(ctx, req.(*GetRouteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProxyAdminService_WatchRoutes_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRoutesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ProxyAdminServiceServer).WatchRoutes(m, &proxyAdminServiceWatchRoutesServer{stream})
+}
+
+type ProxyAdminService_WatchRoutesServer interface {
+	Send(*WatchRoutesResponse) error
+	grpc.ServerStream
+}
+
+type proxyAdminServiceWatchRoutesServer struct {
+	grpc.ServerStream
+}
+
+func (x *proxyAdminServiceWatchRoutesServer) Send(m *WatchRoutesResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _ProxyAdminService_ListSubscriptions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSubscriptionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProxyAdminServiceServer).ListSubscriptions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/onos.proxy.admin.v1.ProxyAdminService/ListSubscriptions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProxyAdminServiceServer).ListSubscriptions(ctx, req.(*ListSubscriptionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// ProxyAdminService_ServiceDesc is the grpc.ServiceDesc for ProxyAdminService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ProxyAdminService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "onos.proxy.admin.v1.ProxyAdminService",
+	HandlerType: (*ProxyAdminServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListE2TInstances",
+			Handler:    _ProxyAdminService_ListE2TInstances_Handler,
+		},
+		{
+			MethodName: "ListE2Nodes",
+			Handler:    _ProxyAdminService_ListE2Nodes_Handler,
+		},
+		{
+			MethodName: "ListControlsRelations",
+			Handler:    _ProxyAdminService_ListControlsRelations_Handler,
+		},
+		{
+			MethodName: "GetRoute",
+			Handler:    _ProxyAdminService_GetRoute_Handler,
+		},
+		{
+			MethodName: "ListSubscriptions",
+			Handler:    _ProxyAdminService_ListSubscriptions_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchRoutes",
+			Handler:       _ProxyAdminService_WatchRoutes_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "admin/v1/admin.proto",
+}
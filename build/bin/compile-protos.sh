@@ -0,0 +1,26 @@
+#!/bin/sh
+
+# SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+#
+# SPDX-License-Identifier: Apache-2.0
+
+# Compiles the proxy API protobuf files; requires protoc, protoc-gen-go and protoc-gen-go-grpc on the PATH
+
+set -e
+
+cd "$(dirname "$0")/../../api"
+
+protoc -I . \
+    --go_out=. --go_opt=paths=source_relative \
+    --go-grpc_out=. --go-grpc_opt=paths=source_relative \
+    admin/v1/admin.proto recording/v1/recording.proto
+
+# protoc-gen-go-grpc does not copy the license header of the .proto files
+for file in admin/v1/admin_grpc.pb.go; do
+    { printf '%s\n' \
+        '// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>' \
+        '//' \
+        '// SPDX-License-Identifier: Apache-2.0' \
+        ''; cat "$file"; } > "$file.tmp"
+    mv "$file.tmp" "$file"
+done
@@ -13,6 +13,7 @@ import (
 	"syscall"
 
 	"github.com/onosproject/onos-lib-go/pkg/logging"
+	"github.com/onosproject/onos-proxy/pkg/cli"
 	"github.com/onosproject/onos-proxy/pkg/manager"
 )
 
@@ -24,20 +25,28 @@ func printVersion() {
 }
 
 func main() {
-	caPath := flag.String("caPath", "", "path to CA certificate")
-	keyPath := flag.String("keyPath", "", "path to client private key")
-	certPath := flag.String("certPath", "", "path to client certificate")
+	// Subcommands inspect a running proxy rather than starting one
+	if len(os.Args) > 1 && cli.IsCommand(os.Args[1]) {
+		if err := cli.GetCommand().Execute(); err != nil {
+			os.Exit(1)
+		}
+		return
+	}
+
+	cfg, err := manager.ParseConfig(os.Args[0], os.Args[1:], os.LookupEnv)
+	if err == flag.ErrHelp {
+		os.Exit(0)
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration: %v\n", err)
+		os.Exit(2)
+	}
+	level, _ := manager.ParseLogLevel(cfg.LogLevel)
+	logging.SetLevel(level)
 
 	//logf.SetLogger(zap.New())
 	printVersion()
 
-	log.Info("Starting onos-proxy")
-	cfg := manager.Config{
-		CAPath:   *caPath,
-		KeyPath:  *keyPath,
-		CertPath: *certPath,
-		GRPCPort: 5151,
-	}
+	log.Infof("Starting onos-proxy with configuration %+v", cfg)
 	mgr := manager.NewManager(cfg)
 	mgr.Run()
 
@@ -45,5 +54,9 @@ func main() {
 	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
 	<-sigCh
 
-	mgr.Close()
+	// A non-zero status reports that requests had to be cut short or resources could not be released
+	if err := mgr.Stop(); err != nil {
+		log.Errorf("Unable to stop cleanly: %v", err)
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,202 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package admin
+
+import (
+	"context"
+	"sort"
+
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/onosproject/onos-lib-go/pkg/logging"
+	"github.com/onosproject/onos-lib-go/pkg/northbound"
+	adminapi "github.com/onosproject/onos-proxy/api/admin/v1"
+	e2v1beta1 "github.com/onosproject/onos-proxy/pkg/e2/v1beta1"
+	"github.com/onosproject/onos-proxy/pkg/e2/v1beta1/balancer"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var log = logging.GetLogger()
+
+// RoutingState provides the routing state tracked by the E2T resolver
+type RoutingState interface {
+	// Snapshot returns a copy of the present state
+	Snapshot() balancer.Snapshot
+	// WatchState returns a channel signalled whenever the state may have changed
+	WatchState(ctx context.Context) <-chan struct{}
+}
+
+// Subscriptions provides the subscriptions forwarded to E2T by the proxy
+type Subscriptions interface {
+	// Subscriptions returns the subscriptions which have not been unsubscribed
+	Subscriptions() []e2v1beta1.Subscription
+}
+
+// NewService creates a new proxy admin service
+func NewService(state RoutingState, subscriptions Subscriptions) northbound.Service {
+	return &Service{
+		state:         state,
+		subscriptions: subscriptions,
+	}
+}
+
+// Service is a Service implementation for the proxy admin service
+type Service struct {
+	northbound.Service
+	state         RoutingState
+	subscriptions Subscriptions
+}
+
+// Register registers the Service with the gRPC server.
+func (s Service) Register(r *grpc.Server) {
+	adminapi.RegisterProxyAdminServiceServer(r, &Server{state: s.state, subscriptions: s.subscriptions})
+}
+
+// Server implements the gRPC proxy admin service
+type Server struct {
+	adminapi.UnimplementedProxyAdminServiceServer
+	state         RoutingState
+	subscriptions Subscriptions
+}
+
+func (s *Server) ListE2TInstances(ctx context.Context, request *adminapi.ListE2TInstancesRequest) (*adminapi.ListE2TInstancesResponse, error) {
+	snapshot := s.state.Snapshot()
+	instances := make([]*adminapi.E2TInstance, 0, len(snapshot.E2TInstances))
+	for _, instance := range snapshot.E2TInstances {
+		instances = append(instances, &adminapi.E2TInstance{
+			Id:            instance.ID,
+			Address:       instance.Address,
+			MasteredNodes: instance.MasteredNodes,
+		})
+	}
+	return &adminapi.ListE2TInstancesResponse{Instances: instances}, nil
+}
+
+func (s *Server) ListE2Nodes(ctx context.Context, request *adminapi.ListE2NodesRequest) (*adminapi.ListE2NodesResponse, error) {
+	snapshot := s.state.Snapshot()
+	nodes := make([]*adminapi.E2Node, 0, len(snapshot.E2Nodes))
+	for _, node := range snapshot.E2Nodes {
+		nodes = append(nodes, newE2Node(node))
+	}
+	return &adminapi.ListE2NodesResponse{Nodes: nodes}, nil
+}
+
+func (s *Server) ListControlsRelations(ctx context.Context, request *adminapi.ListControlsRelationsRequest) (*adminapi.ListControlsRelationsResponse, error) {
+	snapshot := s.state.Snapshot()
+	relations := make([]*adminapi.ControlsRelation, 0, len(snapshot.ControlsRelations))
+	for _, relation := range snapshot.ControlsRelations {
+		relations = append(relations, &adminapi.ControlsRelation{
+			Id:       relation.ID,
+			E2TId:    relation.E2TID,
+			E2NodeId: relation.E2NodeID,
+		})
+	}
+	return &adminapi.ListControlsRelationsResponse{Relations: relations}, nil
+}
+
+func (s *Server) GetRoute(ctx context.Context, request *adminapi.GetRouteRequest) (*adminapi.GetRouteResponse, error) {
+	if request.E2NodeId == "" {
+		return nil, errors.Status(errors.NewInvalid("E2 node ID is required")).Err()
+	}
+	for _, node := range s.state.Snapshot().E2Nodes {
+		if node.ID == request.E2NodeId {
+			return &adminapi.GetRouteResponse{Node: newE2Node(node)}, nil
+		}
+	}
+	return nil, errors.Status(errors.NewNotFound("E2 node %s not found", request.E2NodeId)).Err()
+}
+
+func (s *Server) WatchRoutes(request *adminapi.WatchRoutesRequest, server adminapi.ProxyAdminService_WatchRoutesServer) error {
+	ch := s.state.WatchState(server.Context())
+	routes := routesOf(s.state.Snapshot())
+	if !request.Noreplay {
+		for _, response := range routeEvents(nil, routes) {
+			response.Type = adminapi.RouteEventType_NONE
+			if err := send(server, response); err != nil {
+				return err
+			}
+		}
+	}
+
+	for range ch {
+		next := routesOf(s.state.Snapshot())
+		for _, response := range routeEvents(routes, next) {
+			if err := send(server, response); err != nil {
+				return err
+			}
+		}
+		routes = next
+	}
+	return nil
+}
+
+func (s *Server) ListSubscriptions(ctx context.Context, request *adminapi.ListSubscriptionsRequest) (*adminapi.ListSubscriptionsResponse, error) {
+	forwarded := s.subscriptions.Subscriptions()
+	subscriptions := make([]*adminapi.Subscription, 0, len(forwarded))
+	for _, subscription := range forwarded {
+		subscriptions = append(subscriptions, &adminapi.Subscription{
+			TransactionId:       string(subscription.TransactionID),
+			AppId:               string(subscription.Headers.AppID),
+			AppInstanceId:       string(subscription.Headers.AppInstanceID),
+			E2NodeId:            string(subscription.Headers.E2NodeID),
+			ServiceModelName:    string(subscription.Headers.ServiceModel.Name),
+			ServiceModelVersion: string(subscription.Headers.ServiceModel.Version),
+			StartTime:           timestamppb.New(subscription.StartTime),
+			Indications:         subscription.Indications,
+			Streams:             uint32(subscription.Streams),
+			Shared:              subscription.Shared,
+			Status:              string(subscription.Status),
+		})
+	}
+	return &adminapi.ListSubscriptionsResponse{Subscriptions: subscriptions}, nil
+}
+
+func send(server adminapi.ProxyAdminService_WatchRoutesServer, response *adminapi.WatchRoutesResponse) error {
+	log.Debugf("Sending WatchRoutesResponse %+v", response)
+	return server.Send(response)
+}
+
+// routesOf returns the routable E2 nodes in the given snapshot, keyed by ID
+func routesOf(snapshot balancer.Snapshot) map[string]balancer.E2Node {
+	routes := make(map[string]balancer.E2Node)
+	for _, node := range snapshot.E2Nodes {
+		if node.MasterAddress != "" {
+			routes[node.ID] = node
+		}
+	}
+	return routes
+}
+
+// routeEvents returns the events describing the changes between the given routes, ordered by E2 node ID
+func routeEvents(prev, next map[string]balancer.E2Node) []*adminapi.WatchRoutesResponse {
+	var events []*adminapi.WatchRoutesResponse
+	for id, node := range next {
+		if prevNode, ok := prev[id]; !ok {
+			events = append(events, &adminapi.WatchRoutesResponse{Type: adminapi.RouteEventType_ADDED, Node: newE2Node(node)})
+		} else if prevNode != node {
+			events = append(events, &adminapi.WatchRoutesResponse{Type: adminapi.RouteEventType_UPDATED, Node: newE2Node(node)})
+		}
+	}
+	for id, node := range prev {
+		if _, ok := next[id]; !ok {
+			events = append(events, &adminapi.WatchRoutesResponse{Type: adminapi.RouteEventType_REMOVED, Node: newE2Node(node)})
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Node.Id < events[j].Node.Id
+	})
+	return events
+}
+
+func newE2Node(node balancer.E2Node) *adminapi.E2Node {
+	return &adminapi.E2Node{
+		Id:             node.ID,
+		MastershipTerm: node.MastershipTerm,
+		MasterId:       node.MasterID,
+		MasterAddress:  node.MasterAddress,
+	}
+}
+
+var _ adminapi.ProxyAdminServiceServer = (*Server)(nil)
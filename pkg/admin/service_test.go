@@ -0,0 +1,236 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package admin
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	e2api "github.com/onosproject/onos-api/go/onos/e2t/e2/v1beta1"
+	adminapi "github.com/onosproject/onos-proxy/api/admin/v1"
+	e2v1beta1 "github.com/onosproject/onos-proxy/pkg/e2/v1beta1"
+	"github.com/onosproject/onos-proxy/pkg/e2/v1beta1/balancer"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type testState struct {
+	snapshot balancer.Snapshot
+	ch       chan struct{}
+	mu       sync.Mutex
+}
+
+func (s *testState) Snapshot() balancer.Snapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.snapshot
+}
+
+func (s *testState) WatchState(ctx context.Context) <-chan struct{} {
+	ch := make(chan struct{})
+	go func() {
+		defer close(ch)
+		for {
+			select {
+			case <-s.ch:
+				ch <- struct{}{}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+func (s *testState) update(snapshot balancer.Snapshot) {
+	s.mu.Lock()
+	s.snapshot = snapshot
+	s.mu.Unlock()
+	s.ch <- struct{}{}
+}
+
+type testSubscriptions []e2v1beta1.Subscription
+
+func (s testSubscriptions) Subscriptions() []e2v1beta1.Subscription {
+	return s
+}
+
+func newTestClient(t *testing.T, state RoutingState) adminapi.ProxyAdminServiceClient {
+	return newTestClientWithSubscriptions(t, state, testSubscriptions{})
+}
+
+func newTestClientWithSubscriptions(t *testing.T, state RoutingState, subscriptions Subscriptions) adminapi.ProxyAdminServiceClient {
+	lis := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	NewService(state, subscriptions).Register(server)
+	go func() {
+		_ = server.Serve(lis)
+	}()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+	return adminapi.NewProxyAdminServiceClient(conn)
+}
+
+var testSnapshot = balancer.Snapshot{
+	E2TInstances: []balancer.E2TInstance{
+		{ID: "e2t-1", Address: "10.0.0.1:5150", MasteredNodes: []string{"e2-1"}},
+		{ID: "e2t-2", Address: "10.0.0.2:5150"},
+	},
+	E2Nodes: []balancer.E2Node{
+		{ID: "e2-1", MastershipTerm: 2, MasterID: "e2t-1", MasterAddress: "10.0.0.1:5150"},
+		{ID: "e2-2", MastershipTerm: 1},
+	},
+	ControlsRelations: []balancer.ControlsRelation{
+		{ID: "e2t-1-e2-1", E2TID: "e2t-1", E2NodeID: "e2-1"},
+	},
+}
+
+func TestList(t *testing.T) {
+	client := newTestClient(t, &testState{snapshot: testSnapshot})
+	ctx := context.Background()
+
+	instances, err := client.ListE2TInstances(ctx, &adminapi.ListE2TInstancesRequest{})
+	assert.NoError(t, err)
+	assert.Len(t, instances.Instances, 2)
+	assert.Equal(t, []string{"e2-1"}, instances.Instances[0].MasteredNodes)
+
+	nodes, err := client.ListE2Nodes(ctx, &adminapi.ListE2NodesRequest{})
+	assert.NoError(t, err)
+	assert.Len(t, nodes.Nodes, 2)
+	assert.Equal(t, uint64(2), nodes.Nodes[0].MastershipTerm)
+
+	relations, err := client.ListControlsRelations(ctx, &adminapi.ListControlsRelationsRequest{})
+	assert.NoError(t, err)
+	assert.Len(t, relations.Relations, 1)
+	assert.Equal(t, "e2t-1", relations.Relations[0].E2TId)
+
+	route, err := client.GetRoute(ctx, &adminapi.GetRouteRequest{E2NodeId: "e2-1"})
+	assert.NoError(t, err)
+	assert.Equal(t, "10.0.0.1:5150", route.Node.MasterAddress)
+
+	route, err = client.GetRoute(ctx, &adminapi.GetRouteRequest{E2NodeId: "e2-2"})
+	assert.NoError(t, err)
+	assert.Empty(t, route.Node.MasterAddress)
+
+	_, err = client.GetRoute(ctx, &adminapi.GetRouteRequest{E2NodeId: "e2-3"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestWatchRoutes(t *testing.T) {
+	state := &testState{snapshot: testSnapshot, ch: make(chan struct{})}
+	client := newTestClient(t, state)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := client.WatchRoutes(ctx, &adminapi.WatchRoutesRequest{})
+	assert.NoError(t, err)
+	response, err := stream.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, adminapi.RouteEventType_NONE, response.Type)
+	assert.Equal(t, "e2-1", response.Node.Id)
+
+	// e2-1 moves to e2t-2 and e2-2 gets a master
+	state.update(balancer.Snapshot{
+		E2Nodes: []balancer.E2Node{
+			{ID: "e2-1", MastershipTerm: 3, MasterID: "e2t-2", MasterAddress: "10.0.0.2:5150"},
+			{ID: "e2-2", MastershipTerm: 2, MasterID: "e2t-1", MasterAddress: "10.0.0.1:5150"},
+		},
+	})
+	response, err = stream.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, adminapi.RouteEventType_UPDATED, response.Type)
+	assert.Equal(t, "10.0.0.2:5150", response.Node.MasterAddress)
+	response, err = stream.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, adminapi.RouteEventType_ADDED, response.Type)
+	assert.Equal(t, "e2-2", response.Node.Id)
+
+	state.update(balancer.Snapshot{
+		E2Nodes: []balancer.E2Node{
+			{ID: "e2-1", MastershipTerm: 3, MasterID: "e2t-2", MasterAddress: "10.0.0.2:5150"},
+		},
+	})
+	response, err = stream.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, adminapi.RouteEventType_REMOVED, response.Type)
+	assert.Equal(t, "e2-2", response.Node.Id)
+
+	// A new mastership term with the same master is a route update too
+	state.update(balancer.Snapshot{
+		E2Nodes: []balancer.E2Node{
+			{ID: "e2-1", MastershipTerm: 4, MasterID: "e2t-2", MasterAddress: "10.0.0.2:5150"},
+		},
+	})
+	response, err = stream.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, adminapi.RouteEventType_UPDATED, response.Type)
+	assert.Equal(t, uint64(4), response.Node.MastershipTerm)
+	assert.Equal(t, "10.0.0.2:5150", response.Node.MasterAddress)
+}
+
+func TestListSubscriptions(t *testing.T) {
+	start := time.Date(2022, 5, 1, 12, 0, 0, 0, time.UTC)
+	client := newTestClientWithSubscriptions(t, &testState{snapshot: testSnapshot}, testSubscriptions{
+		{
+			Headers: e2api.RequestHeaders{
+				AppID:         "onos-kpimon",
+				AppInstanceID: "onos-kpimon-1",
+				E2NodeID:      "e2-1",
+				ServiceModel:  e2api.ServiceModel{Name: "oran-e2sm-kpm", Version: "v2"},
+			},
+			TransactionID: "kpimon-1",
+			StartTime:     start,
+			Indications:   42,
+			Streams:       1,
+			Status:        e2v1beta1.SubscriptionActive,
+		},
+		{
+			Headers: e2api.RequestHeaders{
+				AppID:        "onos-mho",
+				E2NodeID:     "e2-2",
+				ServiceModel: e2api.ServiceModel{Name: "oran-e2sm-mho", Version: "v2"},
+			},
+			TransactionID: "onos-proxy-0123456789abcdef",
+			StartTime:     start,
+			Shared:        true,
+			Status:        e2v1beta1.SubscriptionRestored,
+		},
+	})
+
+	response, err := client.ListSubscriptions(context.Background(), &adminapi.ListSubscriptionsRequest{})
+	assert.NoError(t, err)
+	assert.Len(t, response.Subscriptions, 2)
+	subscription := response.Subscriptions[0]
+	assert.Equal(t, "kpimon-1", subscription.TransactionId)
+	assert.Equal(t, "onos-kpimon", subscription.AppId)
+	assert.Equal(t, "onos-kpimon-1", subscription.AppInstanceId)
+	assert.Equal(t, "e2-1", subscription.E2NodeId)
+	assert.Equal(t, "oran-e2sm-kpm", subscription.ServiceModelName)
+	assert.Equal(t, "v2", subscription.ServiceModelVersion)
+	assert.Equal(t, start, subscription.StartTime.AsTime())
+	assert.Equal(t, uint64(42), subscription.Indications)
+	assert.Equal(t, uint32(1), subscription.Streams)
+	assert.False(t, subscription.Shared)
+	assert.Equal(t, "active", subscription.Status)
+	assert.True(t, response.Subscriptions[1].Shared)
+	assert.Zero(t, response.Subscriptions[1].Streams)
+	assert.Equal(t, "restored", response.Subscriptions[1].Status)
+}
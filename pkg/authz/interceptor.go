@@ -0,0 +1,132 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package authz
+
+import (
+	"context"
+	"strings"
+
+	e2api "github.com/onosproject/onos-api/go/onos/e2t/e2/v1beta1"
+	"github.com/onosproject/onos-lib-go/pkg/auth"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+const (
+	// healthMethods is the prefix of the health service methods, which are exempt from authorization so that
+	// probes need not present an identity
+	healthMethods = "/grpc.health.v1.Health/"
+
+	authorizationHeader = "authorization"
+	bearerPrefix        = "bearer "
+)
+
+// e2Request is an E2 request carrying the target E2 node and service model in its headers
+type e2Request interface {
+	GetHeaders() e2api.RequestHeaders
+}
+
+// UnaryServerInterceptor returns a server interceptor denying the unary calls the given policy does not allow
+func UnaryServerInterceptor(policy *Policy) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := authorize(ctx, policy, info.FullMethod, req); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns a server interceptor denying the streaming calls the given policy does not
+// allow. The method is authorized when the stream is opened and each request as it is received, before the
+// handler can act on it.
+func StreamServerInterceptor(policy *Policy) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := authorize(stream.Context(), policy, info.FullMethod, nil); err != nil {
+			return err
+		}
+		return handler(srv, &authorizingStream{
+			ServerStream: stream,
+			policy:       policy,
+			method:       info.FullMethod,
+		})
+	}
+}
+
+// authorizingStream authorizes the requests received on a stream
+type authorizingStream struct {
+	grpc.ServerStream
+	policy *Policy
+	method string
+}
+
+func (s *authorizingStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return authorize(s.Context(), s.policy, s.method, m)
+}
+
+// authorize returns a PermissionDenied error if the policy does not allow the given call
+func authorize(ctx context.Context, policy *Policy, method string, req interface{}) error {
+	if strings.HasPrefix(method, healthMethods) {
+		return nil
+	}
+	identities, err := getIdentities(ctx)
+	if err != nil {
+		log.Warnf("Denied %s: %v", method, err)
+		return errors.Status(err).Err()
+	}
+	call := Call{
+		Identities: identities,
+		Method:     method,
+	}
+	if request, ok := req.(e2Request); ok {
+		headers := request.GetHeaders()
+		call.Headers = &headers
+	}
+	if err := policy.Authorize(call); err != nil {
+		log.Warnf("Denied %s for %v: %v", method, identities, err)
+		return errors.Status(errors.NewForbidden("permission denied: %v", err)).Err()
+	}
+	return nil
+}
+
+// getIdentities returns the identities the caller has authenticated with: those of its client certificate,
+// if verified, and the subject of its bearer token, if any
+func getIdentities(ctx context.Context) ([]string, error) {
+	var identities []string
+	if p, ok := peer.FromContext(ctx); ok {
+		// Only verified certificates are considered; presented but unverified ones have no verified chains
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(info.State.VerifiedChains) > 0 {
+			cert := info.State.VerifiedChains[0][0]
+			if cert.Subject.CommonName != "" {
+				identities = append(identities, cert.Subject.CommonName)
+			}
+			identities = append(identities, cert.DNSNames...)
+			for _, uri := range cert.URIs {
+				identities = append(identities, uri.String())
+			}
+		}
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get(authorizationHeader) {
+		if len(value) < len(bearerPrefix) || !strings.EqualFold(value[:len(bearerPrefix)], bearerPrefix) {
+			continue
+		}
+		authenticator := &auth.JwtAuthenticator{}
+		claims, err := authenticator.ParseAndValidate(value[len(bearerPrefix):])
+		if err != nil {
+			return nil, errors.NewUnauthorized("invalid bearer token: %v", err)
+		}
+		if subject, err := claims.GetSubject(); err == nil && subject != "" {
+			identities = append(identities, subject)
+		}
+	}
+	return identities, nil
+}
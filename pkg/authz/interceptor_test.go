@@ -0,0 +1,159 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package authz
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"testing"
+	"time"
+
+	e2api "github.com/onosproject/onos-api/go/onos/e2t/e2/v1beta1"
+	"github.com/onosproject/onos-lib-go/pkg/auth"
+	"github.com/onosproject/onos-proxy/pkg/harness"
+	"github.com/onosproject/onos-proxy/pkg/utils/creds"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	testAddress = "onos-proxy:5151"
+	testSecret  = "secret"
+)
+
+// testServer is a fake E2T service behind the authorization interceptors, served with TLS verifying the client
+// certificates issued by the test CA
+type testServer struct {
+	network *harness.Network
+	certs   *harness.Certificates
+}
+
+func newTestServer(t *testing.T, policy *Policy) *testServer {
+	certs, err := harness.NewCertificates(t.TempDir(), "onos-proxy")
+	require.NoError(t, err)
+	reloader, err := creds.NewReloader(certs.CAPath, certs.ServerCertPath, certs.ServerKeyPath)
+	require.NoError(t, err)
+	network := harness.NewNetwork()
+	t.Cleanup(network.Close)
+	network.Serve(testAddress, func(server *grpc.Server) {
+		harness.NewE2TServer(testAddress).Register(server)
+		healthpb.RegisterHealthServer(server, health.NewServer())
+	},
+		grpc.Creds(credentials.NewTLS(reloader.ServerConfig(tls.VerifyClientCertIfGiven))),
+		grpc.UnaryInterceptor(UnaryServerInterceptor(policy)),
+		grpc.StreamInterceptor(StreamServerInterceptor(policy)))
+	return &testServer{
+		network: network,
+		certs:   certs,
+	}
+}
+
+// dial connects to the server, presenting the client certificate if requested
+func (s *testServer) dial(t *testing.T, withCert bool) *grpc.ClientConn {
+	reloader, err := creds.NewReloader(s.certs.CAPath, s.certs.ClientCertPath, s.certs.ClientKeyPath)
+	require.NoError(t, err)
+	tlsConfig := reloader.ClientConfig()
+	if !withCert {
+		tlsConfig.GetClientCertificate = nil
+	}
+	conn, err := grpc.Dial(testAddress,
+		grpc.WithContextDialer(s.network.Dial),
+		grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+	return conn
+}
+
+// newToken returns a JWT for the given subject signed with the given shared secret
+func newToken(subject string, secret string) string {
+	encode := base64.RawURLEncoding.EncodeToString
+	unsigned := fmt.Sprintf("%s.%s", encode([]byte(`{"alg":"HS256","typ":"JWT"}`)), encode([]byte(fmt.Sprintf(`{"sub":%q}`, subject))))
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unsigned))
+	return fmt.Sprintf("%s.%s", unsigned, encode(mac.Sum(nil)))
+}
+
+func withToken(ctx context.Context, token string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+}
+
+func control(ctx context.Context, conn *grpc.ClientConn, nodeID string) error {
+	_, err := e2api.NewControlServiceClient(conn).Control(ctx, &e2api.ControlRequest{
+		Headers: e2api.RequestHeaders{E2NodeID: e2api.E2NodeID(nodeID)},
+	})
+	return err
+}
+
+func subscribe(ctx context.Context, conn *grpc.ClientConn, serviceModel string) error {
+	stream, err := e2api.NewSubscriptionServiceClient(conn).Subscribe(ctx, &e2api.SubscribeRequest{
+		Headers: e2api.RequestHeaders{
+			E2NodeID:     "e2-1",
+			ServiceModel: e2api.ServiceModel{Name: e2api.ServiceModelName(serviceModel)},
+		},
+		TransactionID: "sub-1",
+	})
+	if err != nil {
+		return err
+	}
+	_, err = stream.Recv()
+	return err
+}
+
+func TestCertificateIdentity(t *testing.T) {
+	server := newTestServer(t, &Policy{
+		Apps: []App{{
+			Name:       "test",
+			Identities: []string{"onos-proxy-test-client"},
+			E2Nodes:    []string{"e2-1"},
+			RPCs:       []string{"Control"},
+		}},
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn := server.dial(t, true)
+	assert.NoError(t, control(ctx, conn, "e2-1"))
+	assert.Equal(t, codes.PermissionDenied, status.Code(control(ctx, conn, "e2-2")))
+	assert.Equal(t, codes.PermissionDenied, status.Code(subscribe(ctx, conn, "oran-e2sm-kpm")))
+
+	// Callers without a verified identity are denied, except for health checks
+	conn = server.dial(t, false)
+	assert.Equal(t, codes.PermissionDenied, status.Code(control(ctx, conn, "e2-1")))
+	_, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	assert.NoError(t, err)
+}
+
+func TestTokenIdentity(t *testing.T) {
+	t.Setenv(auth.SharedSecretKey, testSecret)
+	server := newTestServer(t, &Policy{
+		Apps: []App{{
+			Name:          "kpimon",
+			Identities:    []string{"onos-kpimon"},
+			ServiceModels: []string{"oran-e2sm-kpm"},
+		}},
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn := server.dial(t, false)
+
+	// The subscription request is authorized before it is served
+	assert.NoError(t, subscribe(withToken(ctx, newToken("onos-kpimon", testSecret)), conn, "oran-e2sm-kpm"))
+	assert.Equal(t, codes.PermissionDenied, status.Code(subscribe(withToken(ctx, newToken("onos-kpimon", testSecret)), conn, "oran-e2sm-rc")))
+	assert.Equal(t, codes.PermissionDenied, status.Code(subscribe(withToken(ctx, newToken("onos-rimedo", testSecret)), conn, "oran-e2sm-kpm")))
+	assert.Equal(t, codes.Unauthenticated, status.Code(subscribe(withToken(ctx, newToken("onos-kpimon", "forged")), conn, "oran-e2sm-kpm")))
+}
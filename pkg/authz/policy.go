@@ -0,0 +1,173 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package authz authorizes the calls of apps to the proxy services against a local policy.
+package authz
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	e2api "github.com/onosproject/onos-api/go/onos/e2t/e2/v1beta1"
+	"github.com/onosproject/onos-lib-go/pkg/logging"
+	"gopkg.in/yaml.v2"
+)
+
+var log = logging.GetLogger()
+
+// e2Services are the prefixes of the methods of the E2 services, whose requests name the E2 node and service
+// model they target
+var e2Services = []string{
+	"/onos.e2t.e2.v1beta1.ControlService/",
+	"/onos.e2t.e2.v1beta1.SubscriptionService/",
+}
+
+// Policy grants apps access to the proxy services. A call is allowed if any of the apps whose identities match
+// the caller's allows it; calls by callers matching no app are denied.
+type Policy struct {
+	Apps []App `yaml:"apps"`
+}
+
+// App is the access granted to the callers presenting one of the app's identities. Each list of patterns
+// restricts the calls allowed to those matching any of them, and allows all calls if omitted. Patterns may
+// contain '*' wildcards matching any sequence of characters. The calls of an app restricted to some E2 nodes or
+// service models are limited to the E2 services unless its RPCs grant others explicitly, since those calls name
+// no E2 node or service model to check.
+type App struct {
+	// Name names the app in logs
+	Name string `yaml:"name"`
+	// Identities are the identities of the app's callers: the common name, DNS names or URIs of their verified
+	// client certificates, or the subjects of their JWT bearer tokens
+	Identities []string `yaml:"identities"`
+	// E2Nodes are the IDs of the E2 nodes the app may target
+	E2Nodes []string `yaml:"e2Nodes"`
+	// ServiceModels are the service models the app may use, given either by name or by name and version
+	// in the form name:version
+	ServiceModels []string `yaml:"serviceModels"`
+	// RPCs are the methods the app may call, given either by method name, e.g. Subscribe, or by full method
+	// name, e.g. /onos.topo.Topo/Watch
+	RPCs []string `yaml:"rpcs"`
+}
+
+// LoadPolicy loads the policy from the given YAML file
+func LoadPolicy(path string) (*Policy, error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read authorization policy: %v", err)
+	}
+	policy := &Policy{}
+	if err := yaml.UnmarshalStrict(bytes, policy); err != nil {
+		return nil, fmt.Errorf("unable to parse authorization policy %s: %v", path, err)
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid authorization policy %s: %v", path, err)
+	}
+	return policy, nil
+}
+
+// Validate checks that each app of the policy can be matched by some caller
+func (p *Policy) Validate() error {
+	for i, app := range p.Apps {
+		if len(app.Identities) == 0 {
+			return fmt.Errorf("app %d (%s) has no identities", i, app.Name)
+		}
+	}
+	return nil
+}
+
+// Call is a call to a proxy service to authorize
+type Call struct {
+	// Identities are the authenticated identities of the caller
+	Identities []string
+	// Method is the full gRPC method name
+	Method string
+	// Headers are the E2 request headers of the call, if it is an E2 request
+	Headers *e2api.RequestHeaders
+}
+
+// Authorize returns an error describing why the given call is denied, or nil if it is allowed
+func (p *Policy) Authorize(call Call) error {
+	if len(call.Identities) == 0 {
+		return fmt.Errorf("caller presented no verified identity")
+	}
+	err := fmt.Errorf("no app matches identities %v", call.Identities)
+	for _, app := range p.Apps {
+		if matchAny(app.Identities, call.Identities...) {
+			if err = app.authorize(call); err == nil {
+				return nil
+			}
+		}
+	}
+	return err
+}
+
+// authorize returns an error describing why the given call is not allowed for the app
+func (a App) authorize(call Call) error {
+	if !allowed(a.RPCs, call.Method, path.Base(call.Method)) {
+		return fmt.Errorf("%s is not allowed for app %s", call.Method, a.Name)
+	}
+	if call.Headers == nil {
+		if len(a.RPCs) == 0 && (len(a.E2Nodes) > 0 || len(a.ServiceModels) > 0) && !e2Method(call.Method) {
+			return fmt.Errorf("%s is not allowed for app %s, which is restricted to some E2 nodes or service models", call.Method, a.Name)
+		}
+		return nil
+	}
+	if !allowed(a.E2Nodes, string(call.Headers.E2NodeID)) {
+		return fmt.Errorf("E2 node %s is not allowed for app %s", call.Headers.E2NodeID, a.Name)
+	}
+	serviceModel := call.Headers.ServiceModel
+	if !allowed(a.ServiceModels, string(serviceModel.Name), fmt.Sprintf("%s:%s", serviceModel.Name, serviceModel.Version)) {
+		return fmt.Errorf("service model %s:%s is not allowed for app %s", serviceModel.Name, serviceModel.Version, a.Name)
+	}
+	return nil
+}
+
+// e2Method returns whether the method is one of the E2 services
+func e2Method(method string) bool {
+	for _, service := range e2Services {
+		if strings.HasPrefix(method, service) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowed returns whether any of the values matches any of the patterns, or true if there are no patterns
+func allowed(patterns []string, values ...string) bool {
+	return len(patterns) == 0 || matchAny(patterns, values...)
+}
+
+// matchAny returns whether any of the values matches any of the patterns
+func matchAny(patterns []string, values ...string) bool {
+	for _, pattern := range patterns {
+		for _, value := range values {
+			if match(pattern, value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// match returns whether the value matches the pattern, in which '*' matches any sequence of characters
+func match(pattern string, value string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == value
+	}
+	if !strings.HasPrefix(value, parts[0]) {
+		return false
+	}
+	value = value[len(parts[0]):]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(value, part)
+		if i < 0 {
+			return false
+		}
+		value = value[i+len(part):]
+	}
+	return strings.HasSuffix(value, parts[len(parts)-1])
+}
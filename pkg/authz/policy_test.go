@@ -0,0 +1,150 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package authz
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	e2api "github.com/onosproject/onos-api/go/onos/e2t/e2/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		value   string
+		match   bool
+	}{
+		{"e2:1/5153", "e2:1/5153", true},
+		{"e2:1/5153", "e2:1/5154", false},
+		{"*", "", true},
+		{"*", "e2:1/5153", true},
+		{"e2:1/*", "e2:1/5153/1/2", true},
+		{"e2:1/*", "e2:2/5153", false},
+		{"*/5153", "e2:1/5153", true},
+		{"e2:*/5153", "e2:1/5154", false},
+		{"a*b*c", "abc", true},
+		{"a*b*c", "axxbyyc", true},
+		{"a*b*c", "acb", false},
+		{"a*a", "a", false},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.match, match(test.pattern, test.value), "%s matching %s", test.pattern, test.value)
+	}
+}
+
+func headers(nodeID string, serviceModel string, version string) *e2api.RequestHeaders {
+	return &e2api.RequestHeaders{
+		E2NodeID: e2api.E2NodeID(nodeID),
+		ServiceModel: e2api.ServiceModel{
+			Name:    e2api.ServiceModelName(serviceModel),
+			Version: e2api.ServiceModelVersion(version),
+		},
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	policy := &Policy{
+		Apps: []App{
+			{
+				Name:          "kpimon",
+				Identities:    []string{"onos-kpimon", "spiffe://onos/kpimon"},
+				ServiceModels: []string{"oran-e2sm-kpm:v2"},
+				RPCs:          []string{"Subscribe", "Unsubscribe"},
+			},
+			{
+				Name:          "rimedo",
+				Identities:    []string{"rimedo-*"},
+				E2Nodes:       []string{"e2:1/*"},
+				ServiceModels: []string{"oran-e2sm-rc"},
+			},
+			{
+				Name:       "cli",
+				Identities: []string{"operator"},
+				RPCs:       []string{"/onos.topo.Topo/*"},
+			},
+			{
+				Name:       "monitor",
+				Identities: []string{"monitor"},
+				E2Nodes:    []string{"e2:1/*"},
+				RPCs:       []string{"Subscribe", "ListChannels"},
+			},
+		},
+	}
+	const (
+		subscribe    = "/onos.e2t.e2.v1beta1.SubscriptionService/Subscribe"
+		control      = "/onos.e2t.e2.v1beta1.ControlService/Control"
+		listChannels = "/onos.e2t.e2.v1beta1.SubscriptionAdminService/ListChannels"
+		topoUpdate   = "/onos.topo.Topo/Update"
+	)
+	tests := []struct {
+		name    string
+		call    Call
+		allowed bool
+	}{
+		{"matching app", Call{[]string{"onos-kpimon"}, subscribe, headers("e2:1/5153", "oran-e2sm-kpm", "v2")}, true},
+		{"any identity", Call{[]string{"other", "spiffe://onos/kpimon"}, subscribe, headers("e2:2/1", "oran-e2sm-kpm", "v2")}, true},
+		{"rpc not allowed", Call{[]string{"onos-kpimon"}, control, headers("e2:1/5153", "oran-e2sm-kpm", "v2")}, false},
+		{"version not allowed", Call{[]string{"onos-kpimon"}, subscribe, headers("e2:1/5153", "oran-e2sm-kpm", "v1")}, false},
+		{"any version", Call{[]string{"rimedo-1"}, control, headers("e2:1/5153", "oran-e2sm-rc", "v1")}, true},
+		{"e2 node not allowed", Call{[]string{"rimedo-1"}, control, headers("e2:2/5153", "oran-e2sm-rc", "v1")}, false},
+		{"service model not allowed", Call{[]string{"rimedo-1"}, control, headers("e2:1/5153", "oran-e2sm-kpm", "v2")}, false},
+		{"full method name", Call{[]string{"operator"}, "/onos.topo.Topo/Watch", nil}, true},
+		{"other service", Call{[]string{"operator"}, subscribe, headers("e2:1/5153", "oran-e2sm-kpm", "v2")}, false},
+		{"unknown identity", Call{[]string{"onos-kpimon-2"}, subscribe, headers("e2:1/5153", "oran-e2sm-kpm", "v2")}, false},
+		{"no identity", Call{nil, subscribe, headers("e2:1/5153", "oran-e2sm-kpm", "v2")}, false},
+		{"e2 stream opened", Call{[]string{"rimedo-1"}, subscribe, nil}, true},
+		{"admin service with e2 node restriction", Call{[]string{"rimedo-1"}, listChannels, nil}, false},
+		{"topo service with e2 node restriction", Call{[]string{"rimedo-1"}, topoUpdate, nil}, false},
+		{"admin method granted explicitly", Call{[]string{"monitor"}, listChannels, nil}, true},
+		{"admin method not granted", Call{[]string{"monitor"}, topoUpdate, nil}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := policy.Authorize(test.call)
+			if test.allowed {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "policy.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+apps:
+  - name: kpimon
+    identities: [onos-kpimon]
+    e2Nodes: ["*"]
+    serviceModels: [oran-e2sm-kpm:v2]
+    rpcs: [Subscribe, Unsubscribe]
+`), 0644))
+	policy, err := LoadPolicy(path)
+	require.NoError(t, err)
+	assert.Equal(t, []App{{
+		Name:          "kpimon",
+		Identities:    []string{"onos-kpimon"},
+		E2Nodes:       []string{"*"},
+		ServiceModels: []string{"oran-e2sm-kpm:v2"},
+		RPCs:          []string{"Subscribe", "Unsubscribe"},
+	}}, policy.Apps)
+
+	_, err = LoadPolicy(filepath.Join(dir, "missing.yaml"))
+	assert.Error(t, err)
+
+	require.NoError(t, os.WriteFile(path, []byte("apps:\n  - name: kpimon\n    nodes: [\"*\"]\n"), 0644))
+	_, err = LoadPolicy(path)
+	assert.Error(t, err)
+
+	require.NoError(t, os.WriteFile(path, []byte("apps:\n  - name: kpimon\n    rpcs: [Subscribe]\n"), 0644))
+	_, err = LoadPolicy(path)
+	assert.Error(t, err)
+}
@@ -0,0 +1,179 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	e2api "github.com/onosproject/onos-api/go/onos/e2t/e2/v1beta1"
+	"github.com/onosproject/onos-lib-go/pkg/cli"
+	"github.com/onosproject/onos-proxy/pkg/admin"
+	e2v1beta1 "github.com/onosproject/onos-proxy/pkg/e2/v1beta1"
+	"github.com/onosproject/onos-proxy/pkg/e2/v1beta1/balancer"
+	"github.com/onosproject/onos-proxy/pkg/health"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+type testState struct{}
+
+func (s testState) Snapshot() balancer.Snapshot {
+	return balancer.Snapshot{
+		E2TInstances: []balancer.E2TInstance{
+			{ID: "e2t-1", Address: "10.0.0.1:5150", MasteredNodes: []string{"e2-1"}},
+		},
+		E2Nodes: []balancer.E2Node{
+			{ID: "e2-1", MastershipTerm: 2, MasterID: "e2t-1", MasterAddress: "10.0.0.1:5150"},
+			{ID: "e2-2", MastershipTerm: 1},
+		},
+	}
+}
+
+func (s testState) WatchState(ctx context.Context) <-chan struct{} {
+	ch := make(chan struct{})
+	go func() {
+		<-ctx.Done()
+		close(ch)
+	}()
+	return ch
+}
+
+type testSubscriptions struct{}
+
+func (s testSubscriptions) Subscriptions() []e2v1beta1.Subscription {
+	return []e2v1beta1.Subscription{
+		{
+			Headers: e2api.RequestHeaders{
+				AppID:        "onos-kpimon",
+				E2NodeID:     "e2-1",
+				ServiceModel: e2api.ServiceModel{Name: "oran-e2sm-kpm", Version: "v2"},
+			},
+			TransactionID: "kpimon-1",
+			StartTime:     time.Date(2022, 5, 1, 12, 0, 0, 0, time.UTC),
+			Indications:   42,
+			Streams:       1,
+			Status:        e2v1beta1.SubscriptionActive,
+		},
+	}
+}
+
+type testChecker struct {
+	ready error
+}
+
+func (c testChecker) Live() error {
+	return nil
+}
+
+func (c testChecker) Ready() error {
+	return c.ready
+}
+
+func startServer(t *testing.T, checker health.Checker) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	server := grpc.NewServer()
+	admin.NewService(testState{}, testSubscriptions{}).Register(server)
+	health.NewService(health.NewMonitor(checker)).Register(server)
+	go func() {
+		_ = server.Serve(lis)
+	}()
+	t.Cleanup(server.Stop)
+	return lis.Addr().String()
+}
+
+func run(t *testing.T, address string, args ...string) (string, error) {
+	var output bytes.Buffer
+	cli.CaptureOutput(&output)
+	cmd := GetCommand()
+	cmd.SetArgs(append(args, "--service-address", address, "--no-tls"))
+	cmd.SetOut(&output)
+	cmd.SetErr(&output)
+	err := cmd.Execute()
+	return output.String(), err
+}
+
+func TestRoutes(t *testing.T) {
+	address := startServer(t, testChecker{})
+
+	output, err := run(t, address, "routes")
+	assert.NoError(t, err)
+	assert.Equal(t, `E2 NODE  TERM  E2T     ADDRESS
+e2-1     2     e2t-1   10.0.0.1:5150
+e2-2     1     <none>  <none>
+`, output)
+
+	output, err = run(t, address, "routes", "e2-1", "-o", "json")
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"id":"e2-1","mastershipTerm":"2","masterId":"e2t-1","masterAddress":"10.0.0.1:5150"}`, output)
+
+	_, err = run(t, address, "routes", "e2-3")
+	assert.Error(t, err)
+
+	output, err = run(t, address, "e2t")
+	assert.NoError(t, err)
+	assert.Equal(t, `E2T    ADDRESS        E2 NODES
+e2t-1  10.0.0.1:5150  e2-1
+`, output)
+}
+
+func TestForwardedSubscriptions(t *testing.T) {
+	address := startServer(t, testChecker{})
+
+	output, err := run(t, address, "subscriptions", "--forwarded", "-o", "json")
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"transactionId":"kpimon-1","appId":"onos-kpimon","appInstanceId":"","e2NodeId":"e2-1",
+"serviceModelName":"oran-e2sm-kpm","serviceModelVersion":"v2","startTime":"2022-05-01T12:00:00Z",
+"indications":"42","streams":1,"shared":false,"status":"active"}`, output)
+}
+
+func TestHealth(t *testing.T) {
+	output, err := run(t, startServer(t, testChecker{}), "health")
+	assert.NoError(t, err)
+	assert.Equal(t, `CHECK      STATUS
+liveness   SERVING
+readiness  SERVING
+`, output)
+
+	_, err = run(t, startServer(t, testChecker{ready: fmt.Errorf("not ready")}), "health", "-o", "json")
+	assert.Error(t, err)
+}
+
+func TestIsCommand(t *testing.T) {
+	assert.True(t, IsCommand("routes"))
+	assert.True(t, IsCommand("log"))
+	assert.False(t, IsCommand("-grpcPort"))
+}
+
+func TestConfigFile(t *testing.T) {
+	address := startServer(t, testChecker{})
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	t.Cleanup(func() {
+		_ = os.Chdir(wd)
+	})
+
+	// The CLI configuration is read from its own file, not from the proxy's
+	config := fmt.Sprintf("service-address: %s\nno-tls: true\n", address)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "onos-proxy-cli.yaml"), []byte(config), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "onos-proxy.yaml"), []byte("grpcPort: 5150\n"), 0644))
+	var output bytes.Buffer
+	cli.CaptureOutput(&output)
+	cmd := GetCommand()
+	cmd.SetArgs([]string{"health"})
+	cmd.SetOut(&output)
+	assert.NoError(t, cmd.Execute())
+	assert.Contains(t, output.String(), "readiness  SERVING")
+}
@@ -0,0 +1,69 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cli
+
+import (
+	"fmt"
+
+	"github.com/onosproject/onos-lib-go/pkg/cli"
+	"github.com/onosproject/onos-proxy/pkg/health"
+	"github.com/spf13/cobra"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func getHealthCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "health",
+		Short: "Check the liveness and readiness of the proxy",
+		Args:  cobra.NoArgs,
+		RunE:  runHealthCommand,
+	}
+}
+
+func runHealthCommand(cmd *cobra.Command, args []string) error {
+	format, err := getOutputFormat(cmd)
+	if err != nil {
+		return err
+	}
+	conn, err := cli.GetConnection(cmd)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	client := healthpb.NewHealthClient(conn)
+
+	checks := []string{health.LivenessService, health.ReadinessService}
+	statuses := make(map[string]string)
+	healthy := true
+	for _, check := range checks {
+		response, err := client.Check(cmd.Context(), &healthpb.HealthCheckRequest{Service: check})
+		if err != nil {
+			return err
+		}
+		statuses[check] = response.Status.String()
+		if response.Status != healthpb.HealthCheckResponse_SERVING {
+			healthy = false
+		}
+	}
+
+	if format == outputJSON {
+		if err := printJSON(statuses); err != nil {
+			return err
+		}
+	} else {
+		table := newTable()
+		printRow(table, "CHECK", "STATUS")
+		for _, check := range checks {
+			printRow(table, check, statuses[check])
+		}
+		if err := table.Flush(); err != nil {
+			return err
+		}
+	}
+	if !healthy {
+		return fmt.Errorf("proxy is not healthy")
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/onosproject/onos-lib-go/pkg/cli"
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// getOutputFormat returns the output format requested via the command flags
+func getOutputFormat(cmd *cobra.Command) (string, error) {
+	format, _ := cmd.Flags().GetString(outputFlag)
+	switch format {
+	case outputTable, outputJSON:
+		return format, nil
+	}
+	return "", fmt.Errorf("invalid output format %q", format)
+}
+
+// newTable returns a writer aligning tab separated columns on the CLI output
+func newTable() *tabwriter.Writer {
+	return tabwriter.NewWriter(cli.GetOutput(), 0, 0, 2, ' ', 0)
+}
+
+// printRow writes a row of tab separated columns
+func printRow(w *tabwriter.Writer, columns ...interface{}) {
+	values := make([]string, len(columns))
+	for i, column := range columns {
+		values[i] = fmt.Sprint(column)
+	}
+	_, _ = fmt.Fprintln(w, strings.Join(values, "\t"))
+}
+
+// printJSON writes the given value as a single line of JSON
+func printJSON(value interface{}) error {
+	var bytes []byte
+	var err error
+	if message, ok := value.(proto.Message); ok {
+		bytes, err = protojson.MarshalOptions{EmitUnpopulated: true}.Marshal(message)
+	} else {
+		bytes, err = json.Marshal(value)
+	}
+	if err != nil {
+		return err
+	}
+	cli.Output("%s\n", bytes)
+	return nil
+}
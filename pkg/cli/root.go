@@ -0,0 +1,69 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cli
+
+import (
+	"github.com/mitchellh/go-homedir"
+	"github.com/onosproject/onos-lib-go/pkg/cli"
+	loggingcli "github.com/onosproject/onos-lib-go/pkg/logging/cli"
+	"github.com/spf13/cobra"
+)
+
+const (
+	defaultAddress = "localhost:5151"
+	// configName is the name of the CLI configuration file, which is distinct from the proxy's own
+	configName = "onos-proxy-cli"
+
+	outputFlag  = "output"
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+// GetCommand returns the root command for inspecting a running proxy, with the defaults of its flags loaded from
+// the CLI configuration file, if any
+func GetCommand() *cobra.Command {
+	initConfig()
+	return newCommand()
+}
+
+// initConfig loads the CLI configuration file from the home directory, /etc/onos or the working directory. It is
+// skipped when there is no home directory, which InitConfig panics on, e.g. in containers running as a user
+// with none.
+func initConfig() {
+	if _, err := homedir.Dir(); err != nil {
+		return
+	}
+	cli.InitConfig(configName)
+}
+
+func newCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "onos-proxy {routes,e2t,subscriptions,health,log} [args]",
+		Short:        "Inspect a running onos-proxy sidecar",
+		SilenceUsage: true,
+	}
+	cli.AddConfigFlags(cmd, defaultAddress)
+	cmd.PersistentFlags().StringP(outputFlag, "o", outputTable, "output format (table, json)")
+
+	cmd.AddCommand(getRoutesCommand())
+	cmd.AddCommand(getE2TCommand())
+	cmd.AddCommand(getSubscriptionsCommand())
+	cmd.AddCommand(getHealthCommand())
+	cmd.AddCommand(loggingcli.GetCommand())
+	return cmd
+}
+
+// IsCommand returns whether the given argument names one of the CLI commands rather than a server flag
+func IsCommand(arg string) bool {
+	if arg == "help" {
+		return true
+	}
+	for _, cmd := range newCommand().Commands() {
+		if cmd.Name() == arg || cmd.HasAlias(arg) {
+			return true
+		}
+	}
+	return false
+}
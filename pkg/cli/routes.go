@@ -0,0 +1,161 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cli
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"github.com/onosproject/onos-lib-go/pkg/cli"
+	adminapi "github.com/onosproject/onos-proxy/api/admin/v1"
+	"github.com/spf13/cobra"
+)
+
+const (
+	watchFlag    = "watch"
+	noReplayFlag = "no-replay"
+)
+
+func getRoutesCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "routes [e2-node-id]",
+		Aliases: []string{"route"},
+		Short:   "List the E2 nodes and the E2T instances requests targeting them are routed to",
+		Args:    cobra.MaximumNArgs(1),
+		RunE:    runRoutesCommand,
+	}
+	cmd.Flags().BoolP(watchFlag, "w", false, "watch for changes in the routes")
+	cmd.Flags().Bool(noReplayFlag, false, "do not replay the present routes when watching")
+	return cmd
+}
+
+func runRoutesCommand(cmd *cobra.Command, args []string) error {
+	format, err := getOutputFormat(cmd)
+	if err != nil {
+		return err
+	}
+	conn, err := cli.GetConnection(cmd)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	client := adminapi.NewProxyAdminServiceClient(conn)
+
+	if watch, _ := cmd.Flags().GetBool(watchFlag); watch {
+		noReplay, _ := cmd.Flags().GetBool(noReplayFlag)
+		return watchRoutes(cmd.Context(), client, noReplay, format)
+	}
+
+	var nodes []*adminapi.E2Node
+	if len(args) > 0 {
+		response, err := client.GetRoute(cmd.Context(), &adminapi.GetRouteRequest{E2NodeId: args[0]})
+		if err != nil {
+			return err
+		}
+		nodes = append(nodes, response.Node)
+	} else {
+		response, err := client.ListE2Nodes(cmd.Context(), &adminapi.ListE2NodesRequest{})
+		if err != nil {
+			return err
+		}
+		nodes = response.Nodes
+	}
+
+	if format == outputJSON {
+		for _, node := range nodes {
+			if err := printJSON(node); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	table := newTable()
+	printRow(table, "E2 NODE", "TERM", "E2T", "ADDRESS")
+	for _, node := range nodes {
+		printRow(table, node.Id, node.MastershipTerm, orNone(node.MasterId), orNone(node.MasterAddress))
+	}
+	return table.Flush()
+}
+
+func watchRoutes(ctx context.Context, client adminapi.ProxyAdminServiceClient, noReplay bool, format string) error {
+	stream, err := client.WatchRoutes(ctx, &adminapi.WatchRoutesRequest{Noreplay: noReplay})
+	if err != nil {
+		return err
+	}
+	table := newTable()
+	if format == outputTable {
+		printRow(table, "EVENT", "E2 NODE", "TERM", "E2T", "ADDRESS")
+	}
+	for {
+		response, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if format == outputJSON {
+			if err := printJSON(response); err != nil {
+				return err
+			}
+			continue
+		}
+		node := response.Node
+		printRow(table, response.Type, node.Id, node.MastershipTerm, orNone(node.MasterId), orNone(node.MasterAddress))
+		if err := table.Flush(); err != nil {
+			return err
+		}
+	}
+}
+
+func getE2TCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "e2t",
+		Short: "List the E2T instances and the E2 nodes each one masters",
+		Args:  cobra.NoArgs,
+		RunE:  runE2TCommand,
+	}
+}
+
+func runE2TCommand(cmd *cobra.Command, args []string) error {
+	format, err := getOutputFormat(cmd)
+	if err != nil {
+		return err
+	}
+	conn, err := cli.GetConnection(cmd)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	client := adminapi.NewProxyAdminServiceClient(conn)
+
+	response, err := client.ListE2TInstances(cmd.Context(), &adminapi.ListE2TInstancesRequest{})
+	if err != nil {
+		return err
+	}
+	if format == outputJSON {
+		for _, instance := range response.Instances {
+			if err := printJSON(instance); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	table := newTable()
+	printRow(table, "E2T", "ADDRESS", "E2 NODES")
+	for _, instance := range response.Instances {
+		printRow(table, instance.Id, instance.Address, orNone(strings.Join(instance.MasteredNodes, ",")))
+	}
+	return table.Flush()
+}
+
+// orNone returns the given value or a placeholder if it is empty
+func orNone(value string) string {
+	if value == "" {
+		return "<none>"
+	}
+	return value
+}
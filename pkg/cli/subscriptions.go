@@ -0,0 +1,119 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cli
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	e2api "github.com/onosproject/onos-api/go/onos/e2t/e2/v1beta1"
+	"github.com/onosproject/onos-lib-go/pkg/cli"
+	adminapi "github.com/onosproject/onos-proxy/api/admin/v1"
+	"github.com/spf13/cobra"
+)
+
+const forwardedFlag = "forwarded"
+
+func getSubscriptionsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "subscriptions",
+		Aliases: []string{"subscription", "subs"},
+		Short:   "List the E2 subscriptions of all E2T instances",
+		Args:    cobra.NoArgs,
+		RunE:    runSubscriptionsCommand,
+	}
+	cmd.Flags().BoolP(watchFlag, "w", false, "watch for changes in the subscriptions")
+	cmd.Flags().Bool(noReplayFlag, false, "do not replay the present subscriptions when watching")
+	cmd.Flags().Bool(forwardedFlag, false, "list the subscriptions forwarded to E2T by the proxy instead")
+	return cmd
+}
+
+func runSubscriptionsCommand(cmd *cobra.Command, args []string) error {
+	format, err := getOutputFormat(cmd)
+	if err != nil {
+		return err
+	}
+	conn, err := cli.GetConnection(cmd)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if forwarded, _ := cmd.Flags().GetBool(forwardedFlag); forwarded {
+		return listForwardedSubscriptions(cmd, adminapi.NewProxyAdminServiceClient(conn), format)
+	}
+	client := e2api.NewSubscriptionAdminServiceClient(conn)
+
+	table := newTable()
+	if format == outputTable {
+		printRow(table, "ID", "REVISION", "E2 NODE", "SERVICE MODEL", "PHASE", "STATE")
+	}
+	output := func(sub *e2api.Subscription) error {
+		if format == outputJSON {
+			return printJSON(sub)
+		}
+		serviceModel := fmt.Sprintf("%s/%s", sub.ServiceModel.Name, sub.ServiceModel.Version)
+		printRow(table, sub.ID, sub.Revision, sub.E2NodeID, serviceModel, sub.Status.Phase, sub.Status.State)
+		return nil
+	}
+
+	if watch, _ := cmd.Flags().GetBool(watchFlag); watch {
+		noReplay, _ := cmd.Flags().GetBool(noReplayFlag)
+		stream, err := client.WatchSubscriptions(cmd.Context(), &e2api.WatchSubscriptionsRequest{NoReplay: noReplay})
+		if err != nil {
+			return err
+		}
+		for {
+			response, err := stream.Recv()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := output(&response.Event.Subscription); err != nil {
+				return err
+			}
+			if err := table.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	response, err := client.ListSubscriptions(cmd.Context(), &e2api.ListSubscriptionsRequest{})
+	if err != nil {
+		return err
+	}
+	for i := range response.Subscriptions {
+		if err := output(&response.Subscriptions[i]); err != nil {
+			return err
+		}
+	}
+	return table.Flush()
+}
+
+func listForwardedSubscriptions(cmd *cobra.Command, client adminapi.ProxyAdminServiceClient, format string) error {
+	response, err := client.ListSubscriptions(cmd.Context(), &adminapi.ListSubscriptionsRequest{})
+	if err != nil {
+		return err
+	}
+	if format == outputJSON {
+		for _, sub := range response.Subscriptions {
+			if err := printJSON(sub); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	table := newTable()
+	printRow(table, "TRANSACTION", "APP", "E2 NODE", "SERVICE MODEL", "SHARED", "STATUS", "STREAMS", "INDICATIONS", "STARTED")
+	for _, sub := range response.Subscriptions {
+		serviceModel := fmt.Sprintf("%s/%s", sub.ServiceModelName, sub.ServiceModelVersion)
+		started := sub.StartTime.AsTime().Local().Format(time.RFC3339)
+		printRow(table, sub.TransactionId, orNone(sub.AppId), sub.E2NodeId, serviceModel, sub.Shared, sub.Status, sub.Streams, sub.Indications, started)
+	}
+	return table.Flush()
+}
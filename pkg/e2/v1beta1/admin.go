@@ -0,0 +1,397 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package v1beta1
+
+import (
+	"context"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	e2api "github.com/onosproject/onos-api/go/onos/e2t/e2/v1beta1"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// deletedEventRetention is how long the events for deleted objects are de-duplicated after the first one
+const deletedEventRetention = time.Minute
+
+// fanOut concurrently invokes the given function once for each known E2T instance, passing it a context
+// which routes requests to that instance. The first error returned by any invocation cancels the
+// remaining invocations and is returned: a result missing the objects of an unavailable instance could not be
+// told apart from a complete one, so the call fails instead, for the client to retry.
+func (s *ProxyServer) fanOut(ctx context.Context, f func(ctx context.Context, address string) error) error {
+	addresses := s.instances.E2TAddresses()
+	if len(addresses) == 0 {
+		return errors.Status(errors.NewUnavailable("no E2T instances available")).Err()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var firstErr error
+	once := &sync.Once{}
+	wg := &sync.WaitGroup{}
+	for _, address := range addresses {
+		wg.Add(1)
+		go func(address string) {
+			defer wg.Done()
+			instanceCtx := metadata.AppendToOutgoingContext(ctx, e2tAddressHeader, address)
+			if err := f(instanceCtx, address); err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}(address)
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// watchAll concurrently invokes the given watch function once for each known E2T instance, and for each instance
+// added later on, passing it a context which routes requests to that instance. The watch of an instance ends with
+// the instance: it is restarted if the instance is known again after its watch ended, and its error is ignored if
+// the instance is no longer known. Any other error cancels the remaining watches and is returned. Watches should
+// wait for ready, as the connections to the instances just added may not be ready yet.
+func (s *ProxyServer) watchAll(ctx context.Context, watch func(ctx context.Context, address string) error) error {
+	if len(s.instances.E2TAddresses()) == 0 {
+		return errors.Status(errors.NewUnavailable("no E2T instances available")).Err()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stateCh := s.instances.WatchState(ctx)
+
+	errCh := make(chan error, 1)
+	doneCh := make(chan string)
+	wg := &sync.WaitGroup{}
+	watches := make(map[string]bool)
+	start := func() {
+		for _, address := range s.instances.E2TAddresses() {
+			if watches[address] {
+				continue
+			}
+			watches[address] = true
+			wg.Add(1)
+			go func(address string) {
+				defer wg.Done()
+				instanceCtx := metadata.AppendToOutgoingContext(ctx, e2tAddressHeader, address)
+				if err := watch(instanceCtx, address); err != nil && s.knows(address) {
+					select {
+					case errCh <- err:
+					default:
+					}
+				}
+				select {
+				case doneCh <- address:
+				case <-ctx.Done():
+				}
+			}(address)
+		}
+	}
+
+	start()
+	var err error
+	for err == nil {
+		select {
+		case _, ok := <-stateCh:
+			if ok {
+				start()
+			}
+		case address := <-doneCh:
+			delete(watches, address)
+		case err = <-errCh:
+		case <-ctx.Done():
+			err = status.FromContextError(ctx.Err()).Err()
+		}
+	}
+	cancel()
+	wg.Wait()
+	return err
+}
+
+// knows returns whether the E2T instance with the given address is presently known
+func (s *ProxyServer) knows(address string) bool {
+	for _, known := range s.instances.E2TAddresses() {
+		if known == address {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *ProxyServer) GetChannel(ctx context.Context, request *e2api.GetChannelRequest) (*e2api.GetChannelResponse, error) {
+	log.Debugf("GetChannelRequest %+v", request)
+	client := e2api.NewSubscriptionAdminServiceClient(s.conn)
+	var response *e2api.GetChannelResponse
+	mu := &sync.Mutex{}
+	err := s.fanOut(ctx, func(ctx context.Context, address string) error {
+		instanceResponse, err := client.GetChannel(ctx, request)
+		if err != nil {
+			if errors.IsNotFound(errors.FromGRPC(err)) {
+				return nil
+			}
+			log.Warnf("GetChannelRequest %+v to %s error: %s", request, address, err)
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if response == nil || instanceResponse.Channel.Revision > response.Channel.Revision {
+			response = instanceResponse
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if response == nil {
+		return nil, errors.Status(errors.NewNotFound("channel %s not found", request.ChannelID)).Err()
+	}
+	log.Debugf("GetChannelResponse %+v", response)
+	return response, nil
+}
+
+func (s *ProxyServer) ListChannels(ctx context.Context, request *e2api.ListChannelsRequest) (*e2api.ListChannelsResponse, error) {
+	log.Debugf("ListChannelsRequest %+v", request)
+	client := e2api.NewSubscriptionAdminServiceClient(s.conn)
+	channels := make(map[e2api.ChannelID]e2api.Channel)
+	mu := &sync.Mutex{}
+	err := s.fanOut(ctx, func(ctx context.Context, address string) error {
+		instanceResponse, err := client.ListChannels(ctx, request)
+		if err != nil {
+			log.Warnf("ListChannelsRequest %+v to %s error: %s", request, address, err)
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for _, channel := range instanceResponse.Channels {
+			if existing, ok := channels[channel.ID]; !ok || channel.Revision > existing.Revision {
+				channels[channel.ID] = channel
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &e2api.ListChannelsResponse{
+		Channels: make([]e2api.Channel, 0, len(channels)),
+	}
+	for _, channel := range channels {
+		response.Channels = append(response.Channels, channel)
+	}
+	sort.Slice(response.Channels, func(i, j int) bool {
+		return response.Channels[i].ID < response.Channels[j].ID
+	})
+	log.Debugf("ListChannelsResponse %+v", response)
+	return response, nil
+}
+
+func (s *ProxyServer) WatchChannels(request *e2api.WatchChannelsRequest, server e2api.SubscriptionAdminService_WatchChannelsServer) error {
+	log.Debugf("WatchChannelsRequest %+v", request)
+	client := e2api.NewSubscriptionAdminServiceClient(s.conn)
+	filter := newEventFilter(deletedEventRetention)
+	mu := &sync.Mutex{}
+	return s.watchAll(server.Context(), func(ctx context.Context, address string) error {
+		stream, err := client.WatchChannels(ctx, request, grpc.WaitForReady(true))
+		if err != nil {
+			log.Warnf("WatchChannelsRequest %+v to %s error: %s", request, address, err)
+			return err
+		}
+		for {
+			response, err := stream.Recv()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				log.Warnf("WatchChannelsRequest %+v to %s error: %s", request, address, err)
+				return err
+			}
+			event := response.Event
+			mu.Lock()
+			if filter.accept(string(event.Channel.ID), event.Channel.Revision, event.Type == e2api.ChannelEventType_CHANNEL_DELETED) {
+				log.Debugf("WatchChannelsResponse %+v", response)
+				err = server.Send(response)
+			}
+			mu.Unlock()
+			if err != nil {
+				log.Warnf("WatchChannelsResponse %+v error: %s", response, err)
+				return err
+			}
+		}
+	})
+}
+
+func (s *ProxyServer) GetSubscription(ctx context.Context, request *e2api.GetSubscriptionRequest) (*e2api.GetSubscriptionResponse, error) {
+	log.Debugf("GetSubscriptionRequest %+v", request)
+	client := e2api.NewSubscriptionAdminServiceClient(s.conn)
+	var response *e2api.GetSubscriptionResponse
+	mu := &sync.Mutex{}
+	err := s.fanOut(ctx, func(ctx context.Context, address string) error {
+		instanceResponse, err := client.GetSubscription(ctx, request)
+		if err != nil {
+			if errors.IsNotFound(errors.FromGRPC(err)) {
+				return nil
+			}
+			log.Warnf("GetSubscriptionRequest %+v to %s error: %s", request, address, err)
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if response == nil || instanceResponse.Subscription.Revision > response.Subscription.Revision {
+			response = instanceResponse
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if response == nil {
+		return nil, errors.Status(errors.NewNotFound("subscription %s not found", request.SubscriptionID)).Err()
+	}
+	log.Debugf("GetSubscriptionResponse %+v", response)
+	return response, nil
+}
+
+func (s *ProxyServer) ListSubscriptions(ctx context.Context, request *e2api.ListSubscriptionsRequest) (*e2api.ListSubscriptionsResponse, error) {
+	log.Debugf("ListSubscriptionsRequest %+v", request)
+	client := e2api.NewSubscriptionAdminServiceClient(s.conn)
+	subscriptions := make(map[e2api.SubscriptionID]e2api.Subscription)
+	mu := &sync.Mutex{}
+	err := s.fanOut(ctx, func(ctx context.Context, address string) error {
+		instanceResponse, err := client.ListSubscriptions(ctx, request)
+		if err != nil {
+			log.Warnf("ListSubscriptionsRequest %+v to %s error: %s", request, address, err)
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for _, subscription := range instanceResponse.Subscriptions {
+			if existing, ok := subscriptions[subscription.ID]; !ok || subscription.Revision > existing.Revision {
+				subscriptions[subscription.ID] = subscription
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &e2api.ListSubscriptionsResponse{
+		Subscriptions: make([]e2api.Subscription, 0, len(subscriptions)),
+	}
+	for _, subscription := range subscriptions {
+		response.Subscriptions = append(response.Subscriptions, subscription)
+	}
+	sort.Slice(response.Subscriptions, func(i, j int) bool {
+		return response.Subscriptions[i].ID < response.Subscriptions[j].ID
+	})
+	log.Debugf("ListSubscriptionsResponse %+v", response)
+	return response, nil
+}
+
+func (s *ProxyServer) WatchSubscriptions(request *e2api.WatchSubscriptionsRequest, server e2api.SubscriptionAdminService_WatchSubscriptionsServer) error {
+	log.Debugf("WatchSubscriptionsRequest %+v", request)
+	client := e2api.NewSubscriptionAdminServiceClient(s.conn)
+	filter := newEventFilter(deletedEventRetention)
+	mu := &sync.Mutex{}
+	return s.watchAll(server.Context(), func(ctx context.Context, address string) error {
+		stream, err := client.WatchSubscriptions(ctx, request, grpc.WaitForReady(true))
+		if err != nil {
+			log.Warnf("WatchSubscriptionsRequest %+v to %s error: %s", request, address, err)
+			return err
+		}
+		for {
+			response, err := stream.Recv()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				log.Warnf("WatchSubscriptionsRequest %+v to %s error: %s", request, address, err)
+				return err
+			}
+			event := response.Event
+			mu.Lock()
+			if filter.accept(string(event.Subscription.ID), event.Subscription.Revision, event.Type == e2api.SubscriptionEventType_SUBSCRIPTION_DELETED) {
+				log.Debugf("WatchSubscriptionsResponse %+v", response)
+				err = server.Send(response)
+			}
+			mu.Unlock()
+			if err != nil {
+				log.Warnf("WatchSubscriptionsResponse %+v error: %s", response, err)
+				return err
+			}
+		}
+	})
+}
+
+// eventFilter de-duplicates the events for the same objects received from multiple E2T instances. Deleted objects
+// are forgotten once the retention period expires, by which time the other instances' events for them are
+// expected to have been received.
+type eventFilter struct {
+	objects   map[string]objectState
+	deletes   []deletedObject
+	retention time.Duration
+}
+
+type objectState struct {
+	revision e2api.Revision
+	deleted  bool
+}
+
+// deletedObject is a deleted object, forgotten once its retention period expires
+type deletedObject struct {
+	id       string
+	revision e2api.Revision
+	expiry   time.Time
+}
+
+func newEventFilter(retention time.Duration) *eventFilter {
+	return &eventFilter{
+		objects:   make(map[string]objectState),
+		retention: retention,
+	}
+}
+
+// accept returns whether an event for the given object revision has not yet been seen
+func (f *eventFilter) accept(id string, revision e2api.Revision, deleted bool) bool {
+	now := time.Now()
+	f.expire(now)
+	if state, ok := f.objects[id]; ok {
+		if revision < state.revision || (revision == state.revision && (state.deleted || !deleted)) {
+			return false
+		}
+	}
+	f.objects[id] = objectState{
+		revision: revision,
+		deleted:  deleted,
+	}
+	if deleted {
+		f.deletes = append(f.deletes, deletedObject{
+			id:       id,
+			revision: revision,
+			expiry:   now.Add(f.retention),
+		})
+	}
+	return true
+}
+
+// expire forgets the deleted objects whose retention period has expired at the given time, unless they have
+// been recreated since
+func (f *eventFilter) expire(now time.Time) {
+	var i int
+	for ; i < len(f.deletes) && !now.Before(f.deletes[i].expiry); i++ {
+		deleted := f.deletes[i]
+		if state := f.objects[deleted.id]; state.deleted && state.revision == deleted.revision {
+			delete(f.objects, deleted.id)
+		}
+	}
+	f.deletes = f.deletes[i:]
+}
@@ -0,0 +1,142 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package v1beta1
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	e2api "github.com/onosproject/onos-api/go/onos/e2t/e2/v1beta1"
+	"github.com/onosproject/onos-proxy/pkg/harness"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestEventFilter(t *testing.T) {
+	filter := newEventFilter(time.Minute)
+	assert.True(t, filter.accept("sub-1", 1, false))
+	assert.False(t, filter.accept("sub-1", 1, false))
+	assert.True(t, filter.accept("sub-2", 0, false))
+	assert.True(t, filter.accept("sub-1", 2, false))
+	assert.False(t, filter.accept("sub-1", 1, false))
+	assert.True(t, filter.accept("sub-1", 2, true))
+	assert.False(t, filter.accept("sub-1", 2, true))
+	assert.False(t, filter.accept("sub-1", 2, false))
+	assert.True(t, filter.accept("sub-1", 3, false))
+}
+
+func TestEventFilterRetention(t *testing.T) {
+	filter := newEventFilter(50 * time.Millisecond)
+	assert.True(t, filter.accept("sub-1", 1, false))
+	assert.True(t, filter.accept("sub-1", 1, true))
+	assert.True(t, filter.accept("sub-2", 1, true))
+	assert.True(t, filter.accept("sub-2", 2, false))
+
+	// Deleted objects are forgotten once the retention period expires, unless they were recreated
+	time.Sleep(100 * time.Millisecond)
+	assert.True(t, filter.accept("sub-3", 1, false))
+	assert.NotContains(t, filter.objects, "sub-1")
+	assert.Contains(t, filter.objects, "sub-2")
+	assert.Empty(t, filter.deletes)
+}
+
+func TestWatchChannels(t *testing.T) {
+	env := newTestEnvWithOptions(t, Options{MasterPolicy: WaitForMaster}, "e2t-1")
+	env.topo.SetMaster("e2-1", "e2t-1")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	request1 := newSubscribeRequest("sub-1", "trigger-1")
+	env.subscribe(t, ctx, request1)
+	stream, err := e2api.NewSubscriptionAdminServiceClient(env.conn).WatchChannels(ctx, &e2api.WatchChannelsRequest{})
+	require.NoError(t, err)
+	response, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, e2api.ChannelEventType_CHANNEL_REPLAYED, response.Event.Type)
+	assert.Equal(t, env.e2ts["e2t-1"].ChannelID(request1), response.Event.Channel.ID)
+
+	// The channels of an instance added after the watch started are watched too
+	e2t := harness.NewE2TServer(fmt.Sprintf("e2t-2:%d", testE2TPort))
+	env.network.Serve(e2t.Address(), e2t.Register)
+	env.topo.AddE2T("e2t-2", "e2t-2", testE2TPort)
+	env.topo.SetMaster("e2-2", "e2t-2")
+	request2 := newSubscribeRequest("sub-2", "trigger-2")
+	request2.Headers.E2NodeID = "e2-2"
+	env.subscribe(t, ctx, request2)
+	response, err = stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, e2t.ChannelID(request2), response.Event.Channel.ID)
+
+	_, err = e2api.NewSubscriptionServiceClient(env.conn).Unsubscribe(ctx, &e2api.UnsubscribeRequest{
+		Headers:       request2.Headers,
+		TransactionID: request2.TransactionID,
+	})
+	require.NoError(t, err)
+	response, err = stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, e2api.ChannelEventType_CHANNEL_DELETED, response.Event.Type)
+	assert.Equal(t, e2t.ChannelID(request2), response.Event.Channel.ID)
+}
+
+func TestAdminFanOut(t *testing.T) {
+	env := newTestEnvWithOptions(t, Options{MasterPolicy: WaitForMaster}, "e2t-1", "e2t-2")
+	e2t1, e2t2 := env.e2ts["e2t-1"], env.e2ts["e2t-2"]
+
+	// Both instances report channel-1 and subscription-1, at different revisions, and one of them each of the others
+	channel := func(id e2api.ChannelID, revision e2api.Revision) e2api.Channel {
+		return e2api.Channel{ID: id, ChannelMeta: e2api.ChannelMeta{E2NodeID: "e2-1", Revision: revision}}
+	}
+	subscription := func(id e2api.SubscriptionID, revision e2api.Revision) e2api.Subscription {
+		return e2api.Subscription{ID: id, SubscriptionMeta: e2api.SubscriptionMeta{E2NodeID: "e2-1", Revision: revision}}
+	}
+	e2t1.SetChannel(channel("channel-1", 1))
+	e2t2.SetChannel(channel("channel-1", 2))
+	e2t2.SetChannel(channel("channel-2", 1))
+	e2t1.SetSubscription(subscription("subscription-1", 3))
+	e2t2.SetSubscription(subscription("subscription-1", 1))
+	e2t1.SetSubscription(subscription("subscription-2", 1))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client := e2api.NewSubscriptionAdminServiceClient(env.conn)
+
+	// Objects reported by several instances are listed once, at their highest revision, once both are known
+	assert.Eventually(t, func() bool {
+		channels, err := client.ListChannels(ctx, &e2api.ListChannelsRequest{})
+		if err != nil || len(channels.Channels) != 2 {
+			return false
+		}
+		subscriptions, err := client.ListSubscriptions(ctx, &e2api.ListSubscriptionsRequest{})
+		return err == nil && len(subscriptions.Subscriptions) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	channels, err := client.ListChannels(ctx, &e2api.ListChannelsRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, []e2api.Channel{channel("channel-1", 2), channel("channel-2", 1)}, channels.Channels)
+	subscriptions, err := client.ListSubscriptions(ctx, &e2api.ListSubscriptionsRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, []e2api.Subscription{subscription("subscription-1", 3), subscription("subscription-2", 1)}, subscriptions.Subscriptions)
+
+	// Objects are found on whichever instances report them
+	getChannel, err := client.GetChannel(ctx, &e2api.GetChannelRequest{ChannelID: "channel-1"})
+	require.NoError(t, err)
+	assert.Equal(t, channel("channel-1", 2), getChannel.Channel)
+	getChannel, err = client.GetChannel(ctx, &e2api.GetChannelRequest{ChannelID: "channel-2"})
+	require.NoError(t, err)
+	assert.Equal(t, channel("channel-2", 1), getChannel.Channel)
+	getSubscription, err := client.GetSubscription(ctx, &e2api.GetSubscriptionRequest{SubscriptionID: "subscription-2"})
+	require.NoError(t, err)
+	assert.Equal(t, subscription("subscription-2", 1), getSubscription.Subscription)
+	_, err = client.GetChannel(ctx, &e2api.GetChannelRequest{ChannelID: "channel-3"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	// The list would be incomplete without an instance, so it fails
+	e2t2.Fail(status.Error(codes.Unavailable, "unavailable"))
+	_, err = client.ListChannels(ctx, &e2api.ListChannelsRequest{})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
@@ -0,0 +1,67 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package balancer
+
+import (
+	"fmt"
+)
+
+// Live returns an error if the topo watch goroutine of any resolver built by this builder has terminated
+func (b *ResolverBuilder) Live() error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for r := range b.resolvers {
+		if !r.Running() {
+			return fmt.Errorf("topo watch of the E2T resolver is not running")
+		}
+	}
+	return nil
+}
+
+// Ready returns an error unless a resolver built by this builder has a live topo watch and knows
+// at least one E2T instance mastering E2 nodes
+func (b *ResolverBuilder) Ready() error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if len(b.resolvers) == 0 {
+		return fmt.Errorf("E2T resolver has not been started")
+	}
+	for r := range b.resolvers {
+		if !r.Watching() {
+			// The last error tells apart e.g. a topo service which is not yet up from a rejected certificate
+			if err := r.watchError(); err != nil {
+				return fmt.Errorf("topo watch of the E2T resolver is not connected to %s: %v", r.topoAddress, err)
+			}
+			return fmt.Errorf("topo watch of the E2T resolver is not connected to %s", r.topoAddress)
+		}
+	}
+	for r := range b.resolvers {
+		if len(r.E2TMasters()) > 0 {
+			return nil
+		}
+	}
+	return fmt.Errorf("no E2T instance mastering any E2 node is known")
+}
+
+// Running returns whether the topo watch goroutine of the resolver is running
+func (r *Resolver) Running() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.running
+}
+
+// Watching returns whether the topo watch of the resolver is connected and synchronized
+func (r *Resolver) Watching() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.watching
+}
+
+// watchError returns the error the topo watch of the resolver last failed with, if any
+func (r *Resolver) watchError() error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.watchErr
+}
@@ -0,0 +1,41 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package balancer
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolverHealth(t *testing.T) {
+	builder := NewResolverBuilder("topo:5150")
+	assert.NoError(t, builder.Live())
+	assert.Error(t, builder.Ready())
+
+	r := newTestResolver(builder)
+	r.setRunning(true)
+	r.setWatching(true)
+	assert.NoError(t, builder.Live())
+	assert.Error(t, builder.Ready())
+
+	// An E2T instance mastering no E2 nodes is not sufficient
+	r.handleEvent(e2tEvent("e2t-1", "10.0.0.1"))
+	r.handleEvent(controlsEvent("e2t-1-e2-1", "e2t-1", "e2-1"))
+	assert.Error(t, builder.Ready())
+
+	r.handleEvent(e2NodeEvent("e2-1", 1, "e2t-1-e2-1"))
+	assert.NoError(t, builder.Ready())
+
+	// A disconnected watch makes the resolver unready but it remains live while reconnecting
+	r.setWatching(false)
+	r.setWatchError(fmt.Errorf("connection refused"))
+	assert.NoError(t, builder.Live())
+	assert.ErrorContains(t, builder.Ready(), "connection refused")
+
+	r.setRunning(false)
+	assert.Error(t, builder.Live())
+}
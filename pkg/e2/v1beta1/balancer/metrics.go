@@ -0,0 +1,64 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package balancer
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	metricsNamespace = "onos_proxy"
+	metricsSubsystem = "e2t"
+)
+
+var reconnectsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: metricsNamespace,
+	Subsystem: metricsSubsystem,
+	Name:      "resolver_reconnects_total",
+	Help:      "Total number of times the resolver reconnected its topo watch",
+})
+
+func init() {
+	prometheus.MustRegister(reconnectsTotal)
+}
+
+var (
+	e2tInstancesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, metricsSubsystem, "instances"),
+		"Number of E2T instances tracked by the resolver",
+		nil, nil)
+
+	masteredNodesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "e2", "mastered_nodes"),
+		"Number of E2 nodes mastered by each E2T instance tracked by the resolver",
+		[]string{"e2t_address"}, nil)
+)
+
+// NewCollector creates a Prometheus collector reporting the E2T instances and E2 node mastership
+// tracked by the resolvers built by the given builder
+func NewCollector(builder *ResolverBuilder) prometheus.Collector {
+	return &collector{
+		builder: builder,
+	}
+}
+
+type collector struct {
+	builder *ResolverBuilder
+}
+
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- e2tInstancesDesc
+	ch <- masteredNodesDesc
+}
+
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	snapshot := c.builder.Snapshot()
+	ch <- prometheus.MustNewConstMetric(e2tInstancesDesc, prometheus.GaugeValue, float64(len(snapshot.E2TInstances)))
+	for _, instance := range snapshot.E2TInstances {
+		ch <- prometheus.MustNewConstMetric(masteredNodesDesc, prometheus.GaugeValue, float64(len(instance.MasteredNodes)), instance.Address)
+	}
+}
+
+var _ prometheus.Collector = (*collector)(nil)
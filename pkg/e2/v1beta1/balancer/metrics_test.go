@@ -0,0 +1,35 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package balancer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollector(t *testing.T) {
+	builder := NewResolverBuilder("topo:5150")
+	r := newTestResolver(builder)
+	r.handleEvent(e2tEvent("e2t-1", "10.0.0.1"))
+	r.handleEvent(e2tEvent("e2t-2", "10.0.0.2"))
+	r.handleEvent(controlsEvent("e2t-1-e2-1", "e2t-1", "e2-1"))
+	r.handleEvent(controlsEvent("e2t-1-e2-2", "e2t-1", "e2-2"))
+	r.handleEvent(e2NodeEvent("e2-1", 1, "e2t-1-e2-1"))
+	r.handleEvent(e2NodeEvent("e2-2", 1, "e2t-1-e2-2"))
+
+	expected := `
+# HELP onos_proxy_e2_mastered_nodes Number of E2 nodes mastered by each E2T instance tracked by the resolver
+# TYPE onos_proxy_e2_mastered_nodes gauge
+onos_proxy_e2_mastered_nodes{e2t_address="10.0.0.1:5150"} 2
+onos_proxy_e2_mastered_nodes{e2t_address="10.0.0.2:5150"} 0
+# HELP onos_proxy_e2t_instances Number of E2T instances tracked by the resolver
+# TYPE onos_proxy_e2t_instances gauge
+onos_proxy_e2t_instances 2
+`
+	assert.NoError(t, testutil.CollectAndCompare(NewCollector(builder), strings.NewReader(expected)))
+}
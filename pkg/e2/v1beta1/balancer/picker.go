@@ -5,12 +5,19 @@
 package balancer
 
 import (
+	"fmt"
+
+	"github.com/onosproject/onos-proxy/pkg/tracing"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc/balancer"
 	"google.golang.org/grpc/balancer/base"
 	"google.golang.org/grpc/metadata"
 )
 
-const e2NodeIDHeader = "e2-node-id"
+const (
+	e2NodeIDHeader   = "e2-node-id"
+	e2tAddressHeader = "e2t-address"
+)
 
 func init() {
 	balancer.Register(base.NewBalancerBuilder(ResolverName, &PickerBuilder{}, base.Config{}))
@@ -22,17 +29,27 @@ type PickerBuilder struct{}
 // Build :
 func (p *PickerBuilder) Build(info base.PickerBuildInfo) balancer.Picker {
 	masters := make(map[string]balancer.SubConn)
+	instances := make(map[string]balancer.SubConn)
+	addresses := make(map[balancer.SubConn]string)
+	terms := make(map[string]uint64)
 
 	for sc, scInfo := range info.ReadySCs {
+		instances[scInfo.Address.Addr] = sc
+		addresses[sc] = scInfo.Address.Addr
 		nodes := scInfo.Address.Attributes.Value("nodes").(nodeList)
+		nodeTerms, _ := scInfo.Address.Attributes.Value("terms").(nodeTerms)
 		for _, node := range nodes {
 			log.Debugf("E2 node %s is mastered by E2T %s; conn=%+v", node, scInfo.Address.Addr, sc)
 			masters[node] = sc
+			terms[node] = nodeTerms[node]
 		}
 	}
 	log.Infof("Built new picker for E2T instances: %+v", masters)
 	return &Picker{
-		masters: masters,
+		masters:   masters,
+		instances: instances,
+		addresses: addresses,
+		terms:     terms,
 	}
 }
 
@@ -40,17 +57,38 @@ var _ base.PickerBuilder = (*PickerBuilder)(nil)
 
 // Picker :
 type Picker struct {
-	masters map[string]balancer.SubConn // NodeID string to connection mapping
+	masters   map[string]balancer.SubConn // NodeID string to connection mapping
+	instances map[string]balancer.SubConn // E2T address to connection mapping
+	addresses map[balancer.SubConn]string // connection to E2T address mapping
+	terms     map[string]uint64           // NodeID string to mastership term mapping
 }
 
 // Pick :
 func (p *Picker) Pick(info balancer.PickInfo) (balancer.PickResult, error) {
 	var result balancer.PickResult
 	if md, ok := metadata.FromOutgoingContext(info.Ctx); ok {
-		ids := md.Get(e2NodeIDHeader)
-		if len(ids) > 0 {
-			if subConn, ok := p.masters[ids[0]]; ok {
-				log.Debugf("Picked subconn for %s: %+v", ids[0], subConn)
+		var nodeID string
+		if ids := md.Get(e2NodeIDHeader); len(ids) > 0 {
+			nodeID = ids[0]
+		}
+
+		// Requests targeted at a specific E2T instance take precedence over routing by E2 node
+		addrs := md.Get(e2tAddressHeader)
+		if len(addrs) > 0 {
+			if subConn, ok := p.instances[addrs[0]]; ok {
+				log.Debugf("Picked subconn for E2T %s: %+v", addrs[0], subConn)
+				p.annotate(info, nodeID, subConn)
+				result.SubConn = subConn
+				return result, nil
+			}
+			// A non-status error fails the call with Unavailable unless it waits for ready, in which case it
+			// is retried with the next picker, e.g. once the connection to a newly added instance is ready
+			return result, fmt.Errorf("E2T instance %s is not available", addrs[0])
+		}
+		if nodeID != "" {
+			if subConn, ok := p.masters[nodeID]; ok {
+				log.Debugf("Picked subconn for %s: %+v", nodeID, subConn)
+				p.annotate(info, nodeID, subConn)
 				result.SubConn = subConn
 				return result, nil
 			}
@@ -60,4 +98,19 @@ func (p *Picker) Pick(info balancer.PickInfo) (balancer.PickResult, error) {
 	return result, balancer.ErrNoSubConnAvailable
 }
 
+// annotate records the routing decision on the span of the call being picked for, if any
+func (p *Picker) annotate(info balancer.PickInfo, nodeID string, subConn balancer.SubConn) {
+	span := trace.SpanFromContext(info.Ctx)
+	if !span.IsRecording() {
+		return
+	}
+	span.SetAttributes(tracing.E2TAddressKey.String(p.addresses[subConn]))
+	if nodeID != "" {
+		span.SetAttributes(tracing.E2NodeIDKey.String(nodeID))
+		if term, ok := p.terms[nodeID]; ok && p.masters[nodeID] == subConn {
+			span.SetAttributes(tracing.MastershipTermKey.Int64(int64(term)))
+		}
+	}
+}
+
 var _ balancer.Picker = (*Picker)(nil)
@@ -0,0 +1,80 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package balancer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/onosproject/onos-proxy/pkg/tracing"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/grpc/attributes"
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/resolver"
+)
+
+type testSubConn struct {
+	balancer.SubConn
+	name string
+}
+
+func testSubConnInfo(addr string, terms nodeTerms) base.SubConnInfo {
+	var nodes nodeList
+	for node := range terms {
+		nodes = append(nodes, node)
+	}
+	return base.SubConnInfo{
+		Address: resolver.Address{
+			Addr:       addr,
+			Attributes: attributes.New("nodes", nodes).WithValue("terms", terms),
+		},
+	}
+}
+
+func TestPickerTracing(t *testing.T) {
+	sc1 := &testSubConn{name: "e2t-1"}
+	sc2 := &testSubConn{name: "e2t-2"}
+	picker := (&PickerBuilder{}).Build(base.PickerBuildInfo{
+		ReadySCs: map[balancer.SubConn]base.SubConnInfo{
+			sc1: testSubConnInfo("10.0.0.1:5150", nodeTerms{"e2-1": 3}),
+			sc2: testSubConnInfo("10.0.0.2:5150", nil),
+		},
+	})
+
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+
+	ctx, span := tracer.Start(context.Background(), "Control")
+	ctx = metadata.AppendToOutgoingContext(ctx, e2NodeIDHeader, "e2-1")
+	result, err := picker.Pick(balancer.PickInfo{Ctx: ctx})
+	assert.NoError(t, err)
+	assert.Equal(t, sc1, result.SubConn)
+	span.End()
+
+	// Requests pinned to another instance are not annotated with the mastership term
+	ctx, span = tracer.Start(context.Background(), "Subscribe")
+	ctx = metadata.AppendToOutgoingContext(ctx, e2NodeIDHeader, "e2-1", e2tAddressHeader, "10.0.0.2:5150")
+	result, err = picker.Pick(balancer.PickInfo{Ctx: ctx})
+	assert.NoError(t, err)
+	assert.Equal(t, sc2, result.SubConn)
+	span.End()
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 2)
+	assert.ElementsMatch(t, []attribute.KeyValue{
+		tracing.E2TAddressKey.String("10.0.0.1:5150"),
+		tracing.E2NodeIDKey.String("e2-1"),
+		tracing.MastershipTermKey.Int64(3),
+	}, spans[0].Attributes())
+	assert.ElementsMatch(t, []attribute.KeyValue{
+		tracing.E2TAddressKey.String("10.0.0.2:5150"),
+		tracing.E2NodeIDKey.String("e2-1"),
+	}, spans[1].Attributes())
+}
@@ -7,11 +7,18 @@ package balancer
 import (
 	"context"
 	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
 	"google.golang.org/grpc/credentials/insecure"
 
 	"github.com/onosproject/onos-api/go/onos/topo"
 	"github.com/onosproject/onos-lib-go/pkg/grpc/retry"
 	"github.com/onosproject/onos-lib-go/pkg/logging"
+	proxytopo "github.com/onosproject/onos-proxy/pkg/topo"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/attributes"
 	"google.golang.org/grpc/codes"
@@ -24,12 +31,167 @@ var log = logging.GetLogger()
 const ResolverName = "e2"
 const topoAddress = "onos-topo:5150"
 
+// namespaceParam is the resolver target query parameter naming the namespace of the topo service
+const namespaceParam = "ns"
+
+const (
+	minRetryDelay = 100 * time.Millisecond
+	maxRetryDelay = 10 * time.Second
+)
+
 func init() {
-	resolver.Register(&ResolverBuilder{})
+	resolver.Register(NewResolverBuilder(topoAddress))
+}
+
+// NewResolverBuilder creates a new resolver builder watching the topo service at the given address, unless
+// the resolver target names another one. The given dial options are applied to the topo connections after the
+// defaults, which use the transport credentials and dialer of the E2T connection being resolved.
+func NewResolverBuilder(topoAddress string, dialOpts ...grpc.DialOption) *ResolverBuilder {
+	return &ResolverBuilder{
+		topoAddress: topoAddress,
+		dialOpts:    dialOpts,
+	}
+}
+
+// ResolverBuilder builds resolvers tracking the E2T instances and E2 node mastership via the topo service.
+//
+// Resolver targets take the form e2://[topo-address]/[e2t-address][?ns=namespace]. The target authority is the
+// address of the topo service to watch, defaulting to the builder's topo address. The ns query parameter names
+// the Kubernetes namespace of the topo service, and qualifies the topo host name unless it is already qualified.
+// Resolvers built for different targets watch their own topo service, so a single builder may track several
+// clusters.
+type ResolverBuilder struct {
+	topoAddress string
+	dialOpts    []grpc.DialOption
+	resolvers   map[*Resolver]bool
+	mu          sync.RWMutex
+	// masterListeners are signalled when the master of any E2 node changes, and stateListeners when any of
+	// the state of the resolvers does, e.g. a mastership term or the ID of a master
+	masterListeners listeners
+	stateListeners  listeners
+}
+
+// E2TMaster returns the address of the E2T instance presently mastering the given E2 node
+func (b *ResolverBuilder) E2TMaster(nodeID string) (string, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for r := range b.resolvers {
+		if address, ok := r.E2TMaster(nodeID); ok {
+			return address, true
+		}
+	}
+	return "", false
+}
+
+// E2TMasters returns the addresses of the E2T instances presently mastering each E2 node, keyed by E2 node ID
+func (b *ResolverBuilder) E2TMasters() map[string]string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	masters := make(map[string]string)
+	for r := range b.resolvers {
+		for nodeID, address := range r.E2TMasters() {
+			masters[nodeID] = address
+		}
+	}
+	return masters
+}
+
+// E2NodeExists returns whether the given E2 node is known to any of the resolvers built by this builder
+func (b *ResolverBuilder) E2NodeExists(nodeID string) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for r := range b.resolvers {
+		if r.E2NodeExists(nodeID) {
+			return true
+		}
+	}
+	return false
+}
+
+// WatchE2TMasters returns a channel signalled whenever the E2 node mastership changes; the channel
+// is closed when the given context is done
+func (b *ResolverBuilder) WatchE2TMasters(ctx context.Context) <-chan struct{} {
+	return b.masterListeners.watch(ctx)
+}
+
+// WatchState returns a channel signalled whenever the state of the resolvers, as returned by Snapshot, may
+// have changed; the channel is closed when the given context is done
+func (b *ResolverBuilder) WatchState(ctx context.Context) <-chan struct{} {
+	return b.stateListeners.watch(ctx)
+}
+
+// listeners are channels signalled on changes, which are coalesced for listeners yet to receive the previous
+// signal
+type listeners struct {
+	chs map[chan struct{}]bool
+	mu  sync.Mutex
+}
+
+// watch returns a new listener channel, closed when the given context is done
+func (l *listeners) watch(ctx context.Context) <-chan struct{} {
+	ch := make(chan struct{}, 1)
+	l.mu.Lock()
+	if l.chs == nil {
+		l.chs = make(map[chan struct{}]bool)
+	}
+	l.chs[ch] = true
+	l.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		l.mu.Lock()
+		delete(l.chs, ch)
+		close(ch)
+		l.mu.Unlock()
+	}()
+	return ch
+}
+
+// notify signals all listeners without blocking
+func (l *listeners) notify() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for ch := range l.chs {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// E2TAddresses returns the addresses of all E2T instances known to the resolvers built by this builder
+func (b *ResolverBuilder) E2TAddresses() []string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	addresses := make(map[string]bool)
+	for r := range b.resolvers {
+		for _, address := range r.E2TAddresses() {
+			addresses[address] = true
+		}
+	}
+	list := make([]string, 0, len(addresses))
+	for address := range addresses {
+		list = append(list, address)
+	}
+	sort.Strings(list)
+	return list
 }
 
-// ResolverBuilder :
-type ResolverBuilder struct{}
+func (b *ResolverBuilder) addResolver(r *Resolver) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.resolvers == nil {
+		b.resolvers = make(map[*Resolver]bool)
+	}
+	b.resolvers[r] = true
+}
+
+func (b *ResolverBuilder) removeResolver(r *Resolver) {
+	b.mu.Lock()
+	delete(b.resolvers, r)
+	b.mu.Unlock()
+	b.stateListeners.notify()
+}
 
 // Scheme :
 func (b *ResolverBuilder) Scheme() string {
@@ -37,7 +199,12 @@ func (b *ResolverBuilder) Scheme() string {
 }
 
 // Build :
-func (b *ResolverBuilder) Build(_ resolver.Target, cc resolver.ClientConn, opts resolver.BuildOptions) (resolver.Resolver, error) {
+func (b *ResolverBuilder) Build(target resolver.Target, cc resolver.ClientConn, opts resolver.BuildOptions) (resolver.Resolver, error) {
+	topoAddress, err := b.topoAddressOf(target)
+	if err != nil {
+		return nil, err
+	}
+
 	var dialOpts []grpc.DialOption
 	if opts.DialCreds != nil {
 		dialOpts = append(
@@ -50,6 +217,7 @@ func (b *ResolverBuilder) Build(_ resolver.Target, cc resolver.ClientConn, opts
 	dialOpts = append(dialOpts, grpc.WithUnaryInterceptor(retry.RetryingUnaryClientInterceptor(retry.WithRetryOn(codes.Unavailable))))
 	dialOpts = append(dialOpts, grpc.WithStreamInterceptor(retry.RetryingStreamClientInterceptor(retry.WithRetryOn(codes.Unavailable))))
 	dialOpts = append(dialOpts, grpc.WithContextDialer(opts.Dialer))
+	dialOpts = append(dialOpts, b.dialOpts...)
 
 	topoConn, err := grpc.Dial(topoAddress, dialOpts...)
 	if err != nil {
@@ -60,77 +228,238 @@ func (b *ResolverBuilder) Build(_ resolver.Target, cc resolver.ClientConn, opts
 		fmt.Sprintf(`{"loadBalancingConfig":[{"%s":{}}]}`, ResolverName),
 	)
 
-	log.Infof("Built new resolver")
+	log.Infof("Built new resolver for topo service %s", topoAddress)
 
 	resolver := &Resolver{
+		builder:       b,
 		clientConn:    cc,
+		topoAddress:   topoAddress,
 		topoConn:      topoConn,
 		serviceConfig: serviceConfig,
+		nodes:         make(map[topo.ID]bool),
 		masterships:   make(map[topo.ID]topo.MastershipState),
 		controls:      make(map[topo.ID]topo.ID),
+		targets:       make(map[topo.ID]topo.ID),
 		addresses:     make(map[topo.ID]string),
+		masters:       make(map[string]string),
 	}
-	err = resolver.start()
+	resolver.start()
+	b.addResolver(resolver)
+	return resolver, nil
+}
+
+// topoAddressOf returns the address of the topo service to watch for the given target
+func (b *ResolverBuilder) topoAddressOf(target resolver.Target) (string, error) {
+	address := b.topoAddress
+	if target.URL.Host != "" {
+		address = target.URL.Host
+	}
+	query := target.URL.Query()
+	for param := range query {
+		if param != namespaceParam {
+			return "", fmt.Errorf("unknown parameter %q in target %s", param, target.URL.String())
+		}
+	}
+	host, port, err := net.SplitHostPort(address)
 	if err != nil {
-		return nil, err
+		return "", fmt.Errorf("invalid topo address %q in target %s: %v", address, target.URL.String(), err)
 	}
-	return resolver, nil
+	if ns := query.Get(namespaceParam); ns != "" && !strings.Contains(host, ".") && net.ParseIP(host) == nil {
+		host = fmt.Sprintf("%s.%s", host, ns)
+	}
+	return net.JoinHostPort(host, port), nil
 }
 
 var _ resolver.Builder = (*ResolverBuilder)(nil)
 
 // Resolver :
 type Resolver struct {
+	builder       *ResolverBuilder
 	clientConn    resolver.ClientConn
+	topoAddress   string
 	topoConn      *grpc.ClientConn
 	serviceConfig *serviceconfig.ParseResult
+	nodes         map[topo.ID]bool                 // known E2 nodes
 	masterships   map[topo.ID]topo.MastershipState // E2 node to mastership (controls relation ID)
 	controls      map[topo.ID]topo.ID              // controls relation to E2T ID
+	targets       map[topo.ID]topo.ID              // controls relation to E2 node ID
 	addresses     map[topo.ID]string               // E2T ID to address
+	masters       map[string]string                // E2 node ID to address of its master E2T
+	running       bool                             // whether the topo watch goroutine is running
+	watching      bool                             // whether the topo watch is connected and synchronized
+	watchErr      error                            // the error the topo watch last failed with
+	cancel        context.CancelFunc
+	mu            sync.RWMutex
 }
 
-func (r *Resolver) start() error {
+// E2TMaster returns the address of the E2T instance presently mastering the given E2 node
+func (r *Resolver) E2TMaster(nodeID string) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	address, ok := r.masters[nodeID]
+	return address, ok
+}
+
+// E2TMasters returns the addresses of the E2T instances presently mastering each E2 node, keyed by E2 node ID
+func (r *Resolver) E2TMasters() map[string]string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	masters := make(map[string]string, len(r.masters))
+	for nodeID, address := range r.masters {
+		masters[nodeID] = address
+	}
+	return masters
+}
+
+// E2NodeExists returns whether the given E2 node is known
+func (r *Resolver) E2NodeExists(nodeID string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.nodes[topo.ID(nodeID)]
+}
+
+// E2TAddresses returns the addresses of all known E2T instances
+func (r *Resolver) E2TAddresses() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	addresses := make([]string, 0, len(r.addresses))
+	for _, address := range r.addresses {
+		addresses = append(addresses, address)
+	}
+	return addresses
+}
+
+// start starts tracking the E2T and E2 node state in the background
+func (r *Resolver) start() {
 	log.Infof("Starting resolver")
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.setRunning(true)
+	go r.run(ctx)
+}
+
+// run keeps the resolver state synchronized with the topo service, reconnecting whenever the watch fails
+func (r *Resolver) run(ctx context.Context) {
+	defer r.setRunning(false)
+	delay := minRetryDelay
+	for {
+		start := time.Now()
+		err := r.sync(ctx)
+		r.setWatching(false)
+		r.setWatchError(err)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(start) > maxRetryDelay {
+			delay = minRetryDelay
+		}
+		log.Warnf("Topo watch on %s failed: %v; reconnecting in %s", r.topoAddress, err, delay)
+		reconnectsTotal.Inc()
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return
+		}
+		delay *= 2
+		if delay > maxRetryDelay {
+			delay = maxRetryDelay
+		}
+	}
+}
+
+// sync opens a watch, reconciles the state with the list of present topo objects and then applies the watch events
+func (r *Resolver) sync(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
 	client := topo.NewTopoClient(r.topoConn)
-	request := &topo.WatchRequest{}
-	stream, err := client.Watch(context.Background(), request)
+	stream, err := proxytopo.OpenWatch(ctx, client)
 	if err != nil {
 		return err
 	}
-	go func() {
-		for {
-			response, err := stream.Recv()
-			if err != nil {
-				return
-			}
-			r.handleEvent(response.Event)
+	response, err := client.List(ctx, &topo.ListRequest{})
+	if err != nil {
+		return err
+	}
+	r.reconcile(response.Objects)
+	r.setWatching(true)
+
+	for {
+		response, err := stream.Recv()
+		if err != nil {
+			return err
 		}
-	}()
-	return nil
+		r.handleEvent(response.Event)
+	}
+}
+
+func (r *Resolver) setRunning(running bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.running = running
+}
+
+func (r *Resolver) setWatching(watching bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.watching = watching
+}
+
+func (r *Resolver) setWatchError(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.watchErr = err
+}
+
+// reconcile replaces the state with that derived from the given objects, dropping any E2 nodes, E2T instances
+// and controls relations removed while the watch was disconnected
+func (r *Resolver) reconcile(objects []topo.Object) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nodes = make(map[topo.ID]bool)
+	r.masterships = make(map[topo.ID]topo.MastershipState)
+	r.controls = make(map[topo.ID]topo.ID)
+	r.targets = make(map[topo.ID]topo.ID)
+	r.addresses = make(map[topo.ID]string)
+	for _, object := range objects {
+		r.apply(topo.Event{Type: topo.EventType_NONE, Object: object})
+	}
+	log.Infof("Synchronized resolver with %d E2T instances and %d E2 nodes from %s", len(r.addresses), len(r.nodes), r.topoAddress)
+	r.updateState()
 }
 
 func (r *Resolver) handleEvent(event topo.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.apply(event) {
+		r.updateState()
+	}
+}
+
+// apply updates the state with the given event, returning whether the state may have changed
+func (r *Resolver) apply(event topo.Event) bool {
 	object := event.Object
 	if entity, ok := object.Obj.(*topo.Object_Entity); ok && entity.Entity.KindID == topo.E2NODE {
 		// Track changes in E2 nodes
 		switch event.Type {
 		case topo.EventType_REMOVED:
+			delete(r.nodes, object.ID)
 			delete(r.masterships, object.ID)
 		default:
+			r.nodes[object.ID] = true
 			var mastership topo.MastershipState
 			_ = object.GetAspect(&mastership)
 			if mastership.Term > r.masterships[object.ID].Term {
 				r.masterships[object.ID] = mastership
 			}
 		}
-		r.updateState()
+		return true
 	} else if entity, ok := object.Obj.(*topo.Object_Entity); ok && entity.Entity.KindID == topo.E2T {
 		// Track changes in E2T instances
 		switch event.Type {
 		case topo.EventType_REMOVED:
 			delete(r.addresses, object.ID)
-			r.updateState()
+			return true
 		default:
 			var info topo.E2TInfo
 			_ = object.GetAspect(&info)
@@ -139,8 +468,7 @@ func (r *Resolver) handleEvent(event topo.Event) {
 					address := fmt.Sprintf("%s:%d", iface.IP, iface.Port)
 					if r.addresses[object.ID] != address {
 						r.addresses[object.ID] = address
-						r.updateState()
-						break
+						return true
 					}
 				}
 			}
@@ -150,38 +478,53 @@ func (r *Resolver) handleEvent(event topo.Event) {
 		switch event.Type {
 		case topo.EventType_REMOVED:
 			delete(r.controls, object.ID)
+			delete(r.targets, object.ID)
 		default:
 			r.controls[object.ID] = relation.Relation.SrcEntityID
+			r.targets[object.ID] = relation.Relation.TgtEntityID
 		}
-		r.updateState()
+		return true
 	}
+	return false
 }
 
 func (r *Resolver) updateState() {
 	// Produce list of addresses for available E2T instances
 	// Annotate each address with a list of nodes for which this instances is presently the master
 	e2tE2Nodes := make(map[topo.ID]nodeList)
+	e2tTerms := make(map[topo.ID]nodeTerms)
 
 	// Scan over all nodes and insert their ID into the list of nodes of its master E2T instance
 	for nodeID, mastership := range r.masterships {
 		if e2tID, ok := r.controls[topo.ID(mastership.NodeId)]; ok {
 			e2tE2Nodes[e2tID] = append(e2tE2Nodes[e2tID], string(nodeID))
+			if e2tTerms[e2tID] == nil {
+				e2tTerms[e2tID] = make(nodeTerms)
+			}
+			e2tTerms[e2tID][string(nodeID)] = mastership.Term
 		}
 	}
 
 	// Transpose the map of E2T node IDs into a list of addresses with nodes attribute
+	// Instances presently mastering no nodes are included as well to allow requests targeted at all instances
 	addresses := make([]resolver.Address, 0, len(r.addresses))
+	masters := make(map[string]string)
 	for e2tID, addr := range r.addresses {
-		if nodes, ok := e2tE2Nodes[e2tID]; ok {
-			addresses = append(addresses, resolver.Address{
-				Addr: addr,
-				Attributes: attributes.New(
-					"nodes",
-					nodes,
-				),
-			})
-			log.Debugf("New resolver address: %s => %+v", addr, nodes)
+		nodes := e2tE2Nodes[e2tID]
+		for _, node := range nodes {
+			masters[node] = addr
 		}
+		addresses = append(addresses, resolver.Address{
+			Addr: addr,
+			Attributes: attributes.New(
+				"nodes",
+				nodes,
+			).WithValue(
+				"terms",
+				e2tTerms[e2tID],
+			),
+		})
+		log.Debugf("New resolver address: %s => %+v", addr, nodes)
 	}
 
 	log.Infof("New resolver addresses: %+v", addresses)
@@ -192,6 +535,20 @@ func (r *Resolver) updateState() {
 		Addresses:     addresses,
 		ServiceConfig: r.serviceConfig,
 	})
+
+	// Notify the mastership listeners if the master of any node has changed
+	changed := len(masters) != len(r.masters)
+	for node, addr := range masters {
+		if r.masters[node] != addr {
+			changed = true
+			break
+		}
+	}
+	r.masters = masters
+	if changed {
+		r.builder.masterListeners.notify()
+	}
+	r.builder.stateListeners.notify()
 }
 
 // ResolveNow :
@@ -199,6 +556,10 @@ func (r *Resolver) ResolveNow(resolver.ResolveNowOptions) {}
 
 // Close :
 func (r *Resolver) Close() {
+	r.builder.removeResolver(r)
+	if r.cancel != nil {
+		r.cancel()
+	}
 	if err := r.topoConn.Close(); err != nil {
 		log.Error("failed to close conn", err)
 	}
@@ -222,3 +583,21 @@ func (l nodeList) Equal(o interface{}) bool {
 	}
 	return false
 }
+
+// nodeTerms maps the IDs of the E2 nodes mastered by an E2T instance to their mastership terms
+type nodeTerms map[string]uint64
+
+func (t nodeTerms) Equal(o interface{}) bool {
+	if nt, ok := o.(nodeTerms); ok {
+		if len(t) != len(nt) {
+			return false
+		}
+		for node, term := range t {
+			if other, ok := nt[node]; !ok || other != term {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
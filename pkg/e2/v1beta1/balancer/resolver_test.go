@@ -0,0 +1,263 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package balancer
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/onosproject/onos-api/go/onos/topo"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/serviceconfig"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type testClientConn struct {
+	resolver.ClientConn
+	state resolver.State
+	mu    sync.Mutex
+}
+
+func (c *testClientConn) UpdateState(state resolver.State) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.state = state
+	return nil
+}
+
+func (c *testClientConn) ParseServiceConfig(string) *serviceconfig.ParseResult {
+	return &serviceconfig.ParseResult{}
+}
+
+func newTestResolver(builder *ResolverBuilder) *Resolver {
+	r := &Resolver{
+		builder:     builder,
+		clientConn:  &testClientConn{},
+		nodes:       make(map[topo.ID]bool),
+		masterships: make(map[topo.ID]topo.MastershipState),
+		controls:    make(map[topo.ID]topo.ID),
+		targets:     make(map[topo.ID]topo.ID),
+		addresses:   make(map[topo.ID]string),
+		masters:     make(map[string]string),
+	}
+	builder.addResolver(r)
+	return r
+}
+
+func e2tEvent(id topo.ID, ip string) topo.Event {
+	object := topo.Object{
+		ID:   id,
+		Type: topo.Object_ENTITY,
+		Obj:  &topo.Object_Entity{Entity: &topo.Entity{KindID: topo.E2T}},
+	}
+	_ = object.SetAspect(&topo.E2TInfo{
+		Interfaces: []*topo.Interface{{Type: topo.Interface_INTERFACE_E2T, IP: ip, Port: 5150}},
+	})
+	return topo.Event{Type: topo.EventType_ADDED, Object: object}
+}
+
+func controlsEvent(id topo.ID, e2tID topo.ID, nodeID topo.ID) topo.Event {
+	return topo.Event{
+		Type: topo.EventType_ADDED,
+		Object: topo.Object{
+			ID:   id,
+			Type: topo.Object_RELATION,
+			Obj: &topo.Object_Relation{Relation: &topo.Relation{
+				KindID:      topo.CONTROLS,
+				SrcEntityID: e2tID,
+				TgtEntityID: nodeID,
+			}},
+		},
+	}
+}
+
+func e2NodeEvent(id topo.ID, term uint64, controlsID topo.ID) topo.Event {
+	object := topo.Object{
+		ID:   id,
+		Type: topo.Object_ENTITY,
+		Obj:  &topo.Object_Entity{Entity: &topo.Entity{KindID: topo.E2NODE}},
+	}
+	_ = object.SetAspect(&topo.MastershipState{Term: term, NodeId: string(controlsID)})
+	return topo.Event{Type: topo.EventType_UPDATED, Object: object}
+}
+
+func TestResolverMastership(t *testing.T) {
+	builder := NewResolverBuilder("topo:5150")
+	r := newTestResolver(builder)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := builder.WatchE2TMasters(ctx)
+
+	r.handleEvent(e2tEvent("e2t-1", "10.0.0.1"))
+	r.handleEvent(e2tEvent("e2t-2", "10.0.0.2"))
+	r.handleEvent(controlsEvent("e2t-1-e2-1", "e2t-1", "e2-1"))
+	r.handleEvent(controlsEvent("e2t-2-e2-1", "e2t-2", "e2-1"))
+	assert.Equal(t, []string{"10.0.0.1:5150", "10.0.0.2:5150"}, builder.E2TAddresses())
+
+	_, ok := builder.E2TMaster("e2-1")
+	assert.False(t, ok)
+	assert.False(t, builder.E2NodeExists("e2-1"))
+
+	r.handleEvent(e2NodeEvent("e2-1", 1, "e2t-1-e2-1"))
+	assert.True(t, builder.E2NodeExists("e2-1"))
+	master, ok := builder.E2TMaster("e2-1")
+	assert.True(t, ok)
+	assert.Equal(t, "10.0.0.1:5150", master)
+	waitSignal(t, ch)
+
+	// Stale mastership terms are ignored
+	r.handleEvent(e2NodeEvent("e2-1", 1, "e2t-2-e2-1"))
+	master, _ = builder.E2TMaster("e2-1")
+	assert.Equal(t, "10.0.0.1:5150", master)
+
+	r.handleEvent(e2NodeEvent("e2-1", 2, "e2t-2-e2-1"))
+	master, _ = builder.E2TMaster("e2-1")
+	assert.Equal(t, "10.0.0.2:5150", master)
+	waitSignal(t, ch)
+}
+
+func TestResolverStateChanges(t *testing.T) {
+	builder := NewResolverBuilder("topo:5150")
+	r := newTestResolver(builder)
+	r.handleEvent(e2tEvent("e2t-1", "10.0.0.1"))
+	r.handleEvent(controlsEvent("e2t-1-e2-1", "e2t-1", "e2-1"))
+	r.handleEvent(e2NodeEvent("e2-1", 1, "e2t-1-e2-1"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mastersCh := builder.WatchE2TMasters(ctx)
+	stateCh := builder.WatchState(ctx)
+
+	// A new mastership term with the same master changes the state but not the master
+	r.handleEvent(e2NodeEvent("e2-1", 2, "e2t-1-e2-1"))
+	waitSignal(t, stateCh)
+	assert.Equal(t, uint64(2), builder.Snapshot().E2Nodes[0].MastershipTerm)
+	select {
+	case <-mastersCh:
+		t.Fatal("unexpected mastership change signalled")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func waitSignal(t *testing.T, ch <-chan struct{}) {
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+		t.Fatal("change not signalled")
+	}
+}
+
+func TestResolverResync(t *testing.T) {
+	builder := NewResolverBuilder("topo:5150")
+	r := newTestResolver(builder)
+	r.handleEvent(e2tEvent("e2t-1", "10.0.0.1"))
+	r.handleEvent(e2tEvent("e2t-2", "10.0.0.2"))
+	r.handleEvent(controlsEvent("e2t-1-e2-1", "e2t-1", "e2-1"))
+	r.handleEvent(e2NodeEvent("e2-1", 1, "e2t-1-e2-1"))
+
+	// e2t-1 and its relation vanished while disconnected; e2-1 is now mastered by e2t-2
+	r.reconcile([]topo.Object{
+		e2tEvent("e2t-2", "10.0.0.2").Object,
+		controlsEvent("e2t-2-e2-1", "e2t-2", "e2-1").Object,
+		e2NodeEvent("e2-1", 2, "e2t-2-e2-1").Object,
+	})
+	assert.Equal(t, []string{"10.0.0.2:5150"}, builder.E2TAddresses())
+	master, ok := builder.E2TMaster("e2-1")
+	assert.True(t, ok)
+	assert.Equal(t, "10.0.0.2:5150", master)
+
+	// Removed E2 nodes are dropped as well
+	r.reconcile([]topo.Object{e2tEvent("e2t-2", "10.0.0.2").Object})
+	assert.False(t, builder.E2NodeExists("e2-1"))
+	assert.Empty(t, builder.E2TMasters())
+}
+
+// testTopoServer is a topo service serving a fixed list of objects whose watches can be broken on demand
+type testTopoServer struct {
+	topo.UnimplementedTopoServer
+	objects []topo.Object
+	watches chan context.CancelFunc
+}
+
+func (s *testTopoServer) List(ctx context.Context, request *topo.ListRequest) (*topo.ListResponse, error) {
+	return &topo.ListResponse{Objects: s.objects}, nil
+}
+
+func (s *testTopoServer) Watch(request *topo.WatchRequest, server topo.Topo_WatchServer) error {
+	ctx, cancel := context.WithCancel(server.Context())
+	s.watches <- cancel
+	<-ctx.Done()
+	return status.Error(codes.Unavailable, "watch broken")
+}
+
+func TestResolverReconnect(t *testing.T) {
+	lis := bufconn.Listen(1024 * 1024)
+	topoServer := &testTopoServer{
+		objects: []topo.Object{
+			e2tEvent("e2t-1", "10.0.0.1").Object,
+			controlsEvent("e2t-1-e2-1", "e2t-1", "e2-1").Object,
+			e2NodeEvent("e2-1", 1, "e2t-1-e2-1").Object,
+		},
+		watches: make(chan context.CancelFunc, 10),
+	}
+	server := grpc.NewServer()
+	topo.RegisterTopoServer(server, topoServer)
+	go func() {
+		_ = server.Serve(lis)
+	}()
+	defer server.Stop()
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.NoError(t, err)
+
+	builder := NewResolverBuilder("topo:5150")
+	r := newTestResolver(builder)
+	r.topoConn = conn
+	r.start()
+	defer r.Close()
+
+	breakWatch := func() {
+		select {
+		case cancel := <-topoServer.watches:
+			cancel()
+		case <-time.After(time.Second):
+			t.Fatal("watch not opened")
+		}
+	}
+	awaitMaster := func(address string) {
+		assert.Eventually(t, func() bool {
+			master, _ := builder.E2TMaster("e2-1")
+			return master == address
+		}, time.Second, 10*time.Millisecond)
+	}
+	awaitMaster("10.0.0.1:5150")
+
+	// The E2 node moves to another instance while the watch is broken
+	reconnects := testutil.ToFloat64(reconnectsTotal)
+	topoServer.objects = []topo.Object{
+		e2tEvent("e2t-2", "10.0.0.2").Object,
+		controlsEvent("e2t-2-e2-1", "e2t-2", "e2-1").Object,
+		e2NodeEvent("e2-1", 2, "e2t-2-e2-1").Object,
+	}
+	breakWatch()
+	awaitMaster("10.0.0.2:5150")
+	assert.Equal(t, []string{"10.0.0.2:5150"}, builder.E2TAddresses())
+	assert.Equal(t, reconnects+1, testutil.ToFloat64(reconnectsTotal))
+	assert.True(t, r.Running())
+}
@@ -0,0 +1,152 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package balancer
+
+import (
+	"sort"
+
+	"github.com/onosproject/onos-api/go/onos/topo"
+)
+
+// Snapshot is a consistent point-in-time copy of the E2T instances and E2 node mastership tracked by a resolver
+type Snapshot struct {
+	// E2TInstances are the known E2T instances, sorted by ID
+	E2TInstances []E2TInstance
+	// E2Nodes are the known E2 nodes, sorted by ID
+	E2Nodes []E2Node
+	// ControlsRelations are the known controls relations between E2T instances and E2 nodes, sorted by ID
+	ControlsRelations []ControlsRelation
+}
+
+// E2TInstance is the state of an E2T instance
+type E2TInstance struct {
+	// ID is the topo ID of the E2T instance
+	ID string
+	// Address is the address of the E2T service of the instance
+	Address string
+	// MasteredNodes are the IDs of the E2 nodes presently mastered by the instance, sorted
+	MasteredNodes []string
+}
+
+// E2Node is the mastership state of an E2 node
+type E2Node struct {
+	// ID is the topo ID of the E2 node
+	ID string
+	// MastershipTerm is the term of the present mastership of the E2 node
+	MastershipTerm uint64
+	// MasterID is the ID of the E2T instance presently mastering the E2 node; empty if the node has no master
+	MasterID string
+	// MasterAddress is the address of the E2T instance presently mastering the E2 node; empty if the node has no master
+	MasterAddress string
+}
+
+// ControlsRelation is a controls relation between an E2T instance and an E2 node
+type ControlsRelation struct {
+	// ID is the topo ID of the relation; it is referenced by the mastership state of the E2 node
+	ID string
+	// E2TID is the ID of the E2T instance
+	E2TID string
+	// E2NodeID is the ID of the E2 node
+	E2NodeID string
+}
+
+// Snapshot returns a copy of the state of the resolver
+func (r *Resolver) Snapshot() Snapshot {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	mastered := make(map[string][]string)
+	nodes := make([]E2Node, 0, len(r.nodes))
+	for nodeID := range r.nodes {
+		node := E2Node{ID: string(nodeID)}
+		if mastership, ok := r.masterships[nodeID]; ok {
+			node.MastershipTerm = mastership.Term
+			if e2tID, ok := r.controls[topo.ID(mastership.NodeId)]; ok {
+				if address, ok := r.addresses[e2tID]; ok {
+					node.MasterID = string(e2tID)
+					node.MasterAddress = address
+					mastered[node.MasterID] = append(mastered[node.MasterID], node.ID)
+				}
+			}
+		}
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].ID < nodes[j].ID
+	})
+
+	instances := make([]E2TInstance, 0, len(r.addresses))
+	for e2tID, address := range r.addresses {
+		masteredNodes := mastered[string(e2tID)]
+		sort.Strings(masteredNodes)
+		instances = append(instances, E2TInstance{
+			ID:            string(e2tID),
+			Address:       address,
+			MasteredNodes: masteredNodes,
+		})
+	}
+	sort.Slice(instances, func(i, j int) bool {
+		return instances[i].ID < instances[j].ID
+	})
+
+	relations := make([]ControlsRelation, 0, len(r.controls))
+	for relationID, e2tID := range r.controls {
+		relations = append(relations, ControlsRelation{
+			ID:       string(relationID),
+			E2TID:    string(e2tID),
+			E2NodeID: string(r.targets[relationID]),
+		})
+	}
+	sort.Slice(relations, func(i, j int) bool {
+		return relations[i].ID < relations[j].ID
+	})
+	return Snapshot{
+		E2TInstances:      instances,
+		E2Nodes:           nodes,
+		ControlsRelations: relations,
+	}
+}
+
+// Snapshot returns a copy of the merged state of all resolvers built by this builder
+func (b *ResolverBuilder) Snapshot() Snapshot {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	var snapshot Snapshot
+	instances := make(map[string]bool)
+	nodes := make(map[string]bool)
+	relations := make(map[string]bool)
+	for r := range b.resolvers {
+		resolverSnapshot := r.Snapshot()
+		for _, instance := range resolverSnapshot.E2TInstances {
+			if !instances[instance.ID] {
+				instances[instance.ID] = true
+				snapshot.E2TInstances = append(snapshot.E2TInstances, instance)
+			}
+		}
+		for _, node := range resolverSnapshot.E2Nodes {
+			if !nodes[node.ID] {
+				nodes[node.ID] = true
+				snapshot.E2Nodes = append(snapshot.E2Nodes, node)
+			}
+		}
+		for _, relation := range resolverSnapshot.ControlsRelations {
+			if !relations[relation.ID] {
+				relations[relation.ID] = true
+				snapshot.ControlsRelations = append(snapshot.ControlsRelations, relation)
+			}
+		}
+	}
+	sort.Slice(snapshot.E2TInstances, func(i, j int) bool {
+		return snapshot.E2TInstances[i].ID < snapshot.E2TInstances[j].ID
+	})
+	sort.Slice(snapshot.E2Nodes, func(i, j int) bool {
+		return snapshot.E2Nodes[i].ID < snapshot.E2Nodes[j].ID
+	})
+	sort.Slice(snapshot.ControlsRelations, func(i, j int) bool {
+		return snapshot.ControlsRelations[i].ID < snapshot.ControlsRelations[j].ID
+	})
+	return snapshot
+}
@@ -0,0 +1,115 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package balancer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/onosproject/onos-api/go/onos/topo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshot(t *testing.T) {
+	builder := NewResolverBuilder("topo:5150")
+	r := newTestResolver(builder)
+	r.handleEvent(e2tEvent("e2t-1", "10.0.0.1"))
+	r.handleEvent(e2tEvent("e2t-2", "10.0.0.2"))
+	r.handleEvent(controlsEvent("e2t-1-e2-1", "e2t-1", "e2-1"))
+	r.handleEvent(controlsEvent("e2t-1-e2-2", "e2t-1", "e2-2"))
+	r.handleEvent(e2NodeEvent("e2-2", 3, "e2t-1-e2-2"))
+	r.handleEvent(e2NodeEvent("e2-1", 1, "e2t-1-e2-1"))
+	r.handleEvent(e2NodeEvent("e2-3", 1, "e2t-3-e2-3"))
+
+	snapshot := builder.Snapshot()
+	assert.Equal(t, []E2TInstance{
+		{ID: "e2t-1", Address: "10.0.0.1:5150", MasteredNodes: []string{"e2-1", "e2-2"}},
+		{ID: "e2t-2", Address: "10.0.0.2:5150"},
+	}, snapshot.E2TInstances)
+	assert.Equal(t, []E2Node{
+		{ID: "e2-1", MastershipTerm: 1, MasterID: "e2t-1", MasterAddress: "10.0.0.1:5150"},
+		{ID: "e2-2", MastershipTerm: 3, MasterID: "e2t-1", MasterAddress: "10.0.0.1:5150"},
+		{ID: "e2-3", MastershipTerm: 1},
+	}, snapshot.E2Nodes)
+	assert.Equal(t, []ControlsRelation{
+		{ID: "e2t-1-e2-1", E2TID: "e2t-1", E2NodeID: "e2-1"},
+		{ID: "e2t-1-e2-2", E2TID: "e2t-1", E2NodeID: "e2-2"},
+	}, snapshot.ControlsRelations)
+
+	// Snapshots are copies unaffected by later changes
+	r.handleEvent(topo.Event{Type: topo.EventType_REMOVED, Object: e2tEvent("e2t-1", "10.0.0.1").Object})
+	assert.Len(t, snapshot.E2TInstances, 2)
+	assert.Len(t, builder.Snapshot().E2TInstances, 1)
+}
+
+// TestResolverConcurrency drives events into multiple resolvers while the state is read through every
+// accessor; it is meaningful when run with the race detector
+func TestResolverConcurrency(t *testing.T) {
+	builder := NewResolverBuilder("topo:5150")
+	resolvers := []*Resolver{newTestResolver(builder), newTestResolver(builder)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const iterations = 200
+	var writers sync.WaitGroup
+	for i, r := range resolvers {
+		writers.Add(1)
+		go func(i int, r *Resolver) {
+			defer writers.Done()
+			e2tID := topo.ID(fmt.Sprintf("e2t-%d", i))
+			r.handleEvent(e2tEvent(e2tID, fmt.Sprintf("10.0.0.%d", i)))
+			for j := 0; j < iterations; j++ {
+				nodeID := topo.ID(fmt.Sprintf("e2-%d", j%10))
+				relationID := topo.ID(fmt.Sprintf("%s-%s", e2tID, nodeID))
+				r.handleEvent(controlsEvent(relationID, e2tID, nodeID))
+				r.handleEvent(e2NodeEvent(nodeID, uint64(j+1), relationID))
+				if j%50 == 0 {
+					r.reconcile([]topo.Object{e2tEvent(e2tID, fmt.Sprintf("10.0.0.%d", i)).Object})
+				}
+			}
+		}(i, r)
+	}
+
+	var readers sync.WaitGroup
+	done := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			ch := builder.WatchE2TMasters(ctx)
+			for {
+				select {
+				case <-done:
+					return
+				case <-ch:
+				default:
+				}
+				snapshot := builder.Snapshot()
+				for _, node := range snapshot.E2Nodes {
+					builder.E2TMaster(node.ID)
+					builder.E2NodeExists(node.ID)
+				}
+				builder.E2TAddresses()
+				builder.E2TMasters()
+				_ = builder.Ready()
+				_ = builder.Live()
+			}
+		}()
+	}
+
+	writers.Wait()
+	close(done)
+	readers.Wait()
+
+	snapshot := builder.Snapshot()
+	assert.Len(t, snapshot.E2TInstances, 2)
+	assert.Len(t, snapshot.E2Nodes, 10)
+	for _, node := range snapshot.E2Nodes {
+		assert.NotEmpty(t, node.MasterAddress)
+	}
+}
@@ -0,0 +1,168 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package balancer
+
+import (
+	"context"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	e2api "github.com/onosproject/onos-api/go/onos/e2t/e2/v1beta1"
+	"github.com/onosproject/onos-proxy/pkg/harness"
+	"github.com/onosproject/onos-proxy/pkg/utils/creds"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/resolver"
+)
+
+func TestTopoAddressOf(t *testing.T) {
+	builder := NewResolverBuilder("onos-topo:5150")
+	tests := []struct {
+		target  string
+		address string
+		err     bool
+	}{
+		{target: "e2:///onos-e2t:5150", address: "onos-topo:5150"},
+		{target: "e2://topo-host:5151/onos-e2t:5150", address: "topo-host:5151"},
+		{target: "e2:///onos-e2t:5150?ns=riab", address: "onos-topo.riab:5150"},
+		{target: "e2://topo-host:5150/?ns=riab", address: "topo-host.riab:5150"},
+		{target: "e2://onos-topo.other:5150/?ns=riab", address: "onos-topo.other:5150"},
+		{target: "e2://10.0.0.1:5150/?ns=riab", address: "10.0.0.1:5150"},
+		{target: "e2://topo-host/", err: true},
+		{target: "e2:///onos-e2t:5150?namespace=riab", err: true},
+	}
+	for _, test := range tests {
+		u, err := url.Parse(test.target)
+		require.NoError(t, err)
+		address, err := builder.topoAddressOf(resolver.Target{URL: *u})
+		if test.err {
+			assert.Error(t, err, test.target)
+		} else {
+			assert.NoError(t, err, test.target)
+			assert.Equal(t, test.address, address, test.target)
+		}
+	}
+}
+
+// testCluster is a fake topo service and E2T instance
+type testCluster struct {
+	topo *harness.TopoServer
+	e2t  *harness.E2TServer
+}
+
+func newTestCluster(network *harness.Network, topoAddress string, e2tHost string) *testCluster {
+	cluster := &testCluster{
+		topo: harness.NewTopoServer(),
+		e2t:  harness.NewE2TServer(e2tHost + ":5150"),
+	}
+	network.Serve(topoAddress, cluster.topo.Register)
+	network.Serve(cluster.e2t.Address(), cluster.e2t.Register)
+	cluster.topo.AddE2T("e2t-1", e2tHost, 5150)
+	return cluster
+}
+
+// control sends a control request for the given E2 node, returning the address of the E2T instance which served it
+func control(ctx context.Context, conn *grpc.ClientConn, nodeID string) (string, error) {
+	ctx = metadata.AppendToOutgoingContext(ctx, e2NodeIDHeader, nodeID)
+	response, err := e2api.NewControlServiceClient(conn).Control(ctx, &e2api.ControlRequest{
+		Headers: e2api.RequestHeaders{E2NodeID: e2api.E2NodeID(nodeID)},
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(response.Outcome.Payload), nil
+}
+
+func TestMultipleClusters(t *testing.T) {
+	network := harness.NewNetwork()
+	defer network.Close()
+	clusterA := newTestCluster(network, "topo-a:5150", "e2t-a")
+	clusterB := newTestCluster(network, "topo-b.riab:5150", "e2t-b")
+	clusterA.topo.SetMaster("e2-1", "e2t-1")
+	clusterB.topo.SetMaster("e2-1", "e2t-1")
+
+	// Each connection resolves the E2T instances of the cluster named by its target
+	builder := NewResolverBuilder("onos-topo:5150")
+	connA, err := grpc.Dial("e2://topo-a:5150/onos-e2t:5150", append(network.DialOptions(), grpc.WithResolvers(builder))...)
+	require.NoError(t, err)
+	defer connA.Close()
+	connB, err := grpc.Dial("e2://topo-b:5150/onos-e2t:5150?ns=riab", append(network.DialOptions(), grpc.WithResolvers(builder))...)
+	require.NoError(t, err)
+	defer connB.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	address, err := control(ctx, connA, "e2-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "e2t-a:5150", address)
+	address, err = control(ctx, connB, "e2-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "e2t-b:5150", address)
+	assert.ElementsMatch(t, []string{"e2t-a:5150", "e2t-b:5150"}, builder.E2TAddresses())
+}
+
+func TestResolverDialOptions(t *testing.T) {
+	network := harness.NewNetwork()
+	defer network.Close()
+	cluster := newTestCluster(network, "onos-topo:5150", "e2t-1")
+	cluster.topo.SetMaster("e2-1", "e2t-1")
+
+	var watches int32
+	builder := NewResolverBuilder("onos-topo:5150", grpc.WithChainStreamInterceptor(
+		func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			atomic.AddInt32(&watches, 1)
+			return streamer(ctx, desc, cc, method, opts...)
+		}))
+	conn, err := grpc.Dial("e2:///onos-e2t:5150", append(network.DialOptions(), grpc.WithResolvers(builder))...)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	address, err := control(ctx, conn, "e2-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "e2t-1:5150", address)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&watches))
+}
+
+func TestResolverTLS(t *testing.T) {
+	certs, err := harness.NewCertificates(t.TempDir(), "onos-topo", "onos-e2t")
+	require.NoError(t, err)
+	serverCreds, err := certs.ServerCredentials()
+	require.NoError(t, err)
+	reloader, err := creds.NewReloader(certs.CAPath, certs.ClientCertPath, certs.ClientKeyPath)
+	require.NoError(t, err)
+	tlsConfig := reloader.ClientConfig()
+
+	network := harness.NewNetwork()
+	defer network.Close()
+	topoServer := harness.NewTopoServer()
+	network.Serve("onos-topo:5150", topoServer.Register, grpc.Creds(serverCreds))
+	e2t := harness.NewE2TServer("10.0.0.1:5150")
+	network.Serve(e2t.Address(), e2t.Register, grpc.Creds(serverCreds))
+	topoServer.AddE2T("e2t-1", "10.0.0.1", 5150)
+	topoServer.SetMaster("e2-1", "e2t-1")
+
+	// The topo connection inherits the credentials of the E2T connection, and the E2T instances are
+	// verified against the name of the E2T service rather than their addresses
+	builder := NewResolverBuilder("onos-topo:5150")
+	conn, err := grpc.Dial("e2:///onos-e2t:5150",
+		grpc.WithContextDialer(network.Dial),
+		grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)),
+		grpc.WithResolvers(builder))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	address, err := control(ctx, conn, "e2-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "10.0.0.1:5150", address)
+}
@@ -0,0 +1,199 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package v1beta1
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	e2api "github.com/onosproject/onos-api/go/onos/e2t/e2/v1beta1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultBufferSize is the default number of indications buffered for each subscription stream
+const DefaultBufferSize = 1024
+
+// OverflowPolicy is the policy applied to the indications of a subscription stream whose buffer is full
+type OverflowPolicy string
+
+const (
+	// Block waits for the app to catch up, holding up the upstream subscription stream meanwhile; subscribers of
+	// shared subscriptions are disconnected instead
+	Block OverflowPolicy = "block"
+	// DropOldest drops the oldest buffered indication to make room for the new one
+	DropOldest OverflowPolicy = "drop-oldest"
+	// DropNewest drops the new indication
+	DropNewest OverflowPolicy = "drop-newest"
+	// Disconnect ends the app's stream with a ResourceExhausted error
+	Disconnect OverflowPolicy = "disconnect"
+)
+
+// ParseOverflowPolicy parses the given overflow policy name
+func ParseOverflowPolicy(policy string) (OverflowPolicy, error) {
+	switch OverflowPolicy(policy) {
+	case Block, DropOldest, DropNewest, Disconnect:
+		return OverflowPolicy(policy), nil
+	}
+	return "", fmt.Errorf("invalid overflow policy %q", policy)
+}
+
+func newResponseBuffer(headers e2api.RequestHeaders, size int, policy OverflowPolicy) *responseBuffer {
+	if size <= 0 {
+		size = DefaultBufferSize
+	}
+	if policy == "" {
+		policy = Block
+	}
+	return &responseBuffer{
+		headers: headers,
+		size:    size,
+		policy:  policy,
+		ready:   make(chan struct{}, 1),
+		space:   make(chan struct{}, 1),
+	}
+}
+
+// responseBuffer is a bounded queue of the responses to send on an app's subscription stream, decoupling the
+// upstream subscription from the app. Only indications count towards the size of the buffer and are subject to
+// the overflow policy; acknowledgements are always queued.
+type responseBuffer struct {
+	headers     e2api.RequestHeaders
+	size        int
+	policy      OverflowPolicy
+	responses   []*e2api.SubscribeResponse
+	indications int
+	closed      bool
+	err         error
+	// ready and space signal that responses were queued or dequeued respectively
+	ready chan struct{}
+	space chan struct{}
+	mu    sync.Mutex
+}
+
+// push queues the given response, applying the overflow policy if the buffer is full. It returns an error if
+// the buffer is closed, or if the context is done while blocked.
+func (b *responseBuffer) push(ctx context.Context, response *e2api.SubscribeResponse) error {
+	for {
+		b.mu.Lock()
+		if b.closed {
+			b.mu.Unlock()
+			return fmt.Errorf("subscription stream closed")
+		}
+		indication := response.GetIndication() != nil
+		if !indication || b.indications < b.size {
+			b.enqueue(response)
+			b.mu.Unlock()
+			return nil
+		}
+
+		switch b.policy {
+		case DropOldest:
+			for i, queued := range b.responses {
+				if queued.GetIndication() != nil {
+					b.responses = append(b.responses[:i], b.responses[i+1:]...)
+					b.indications--
+					break
+				}
+			}
+			b.enqueue(response)
+			b.mu.Unlock()
+			observeDroppedIndication(b.headers, b.policy)
+			return nil
+		case DropNewest:
+			b.mu.Unlock()
+			observeDroppedIndication(b.headers, b.policy)
+			return nil
+		case Disconnect:
+			err := status.Errorf(codes.ResourceExhausted, "app not keeping up with subscription: %d indications pending", b.size)
+			b.closeLocked(err, true)
+			b.mu.Unlock()
+			log.Warnf("Disconnecting subscription stream for E2 node %s: %d indications pending", b.headers.E2NodeID, b.size)
+			observeDisconnect(b.headers)
+			return err
+		}
+
+		b.mu.Unlock()
+		select {
+		case <-b.space:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// enqueue appends the response to the buffer; the buffer must be locked
+func (b *responseBuffer) enqueue(response *e2api.SubscribeResponse) {
+	b.responses = append(b.responses, response)
+	if response.GetIndication() != nil {
+		b.indications++
+	}
+	signal(b.ready)
+}
+
+// pop dequeues the next response, waiting for one if the buffer is empty. Once the buffer is closed and
+// drained, it returns the error the buffer was closed with, or io.EOF if none.
+func (b *responseBuffer) pop(ctx context.Context) (*e2api.SubscribeResponse, error) {
+	for {
+		b.mu.Lock()
+		if len(b.responses) > 0 {
+			response := b.responses[0]
+			b.responses[0] = nil
+			b.responses = b.responses[1:]
+			if response.GetIndication() != nil {
+				b.indications--
+			}
+			b.mu.Unlock()
+			signal(b.space)
+			return response, nil
+		}
+		if b.closed {
+			err := b.err
+			b.mu.Unlock()
+			if err == nil {
+				err = io.EOF
+			}
+			return nil, err
+		}
+		b.mu.Unlock()
+		select {
+		case <-b.ready:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// close stops queueing responses; those already queued are still dequeued, then the given error
+func (b *responseBuffer) close(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closeLocked(err, false)
+}
+
+// closeLocked closes the buffer, discarding the queued responses if requested; the buffer must be locked
+func (b *responseBuffer) closeLocked(err error, discard bool) {
+	if b.closed {
+		return
+	}
+	b.closed = true
+	b.err = err
+	if discard {
+		b.responses = nil
+		b.indications = 0
+	}
+	signal(b.ready)
+	signal(b.space)
+}
+
+// signal notifies a waiter on the given channel without blocking
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,136 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package v1beta1
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	e2api "github.com/onosproject/onos-api/go/onos/e2t/e2/v1beta1"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var testBufferHeaders = e2api.RequestHeaders{
+	E2NodeID:     "e2-buffer",
+	ServiceModel: e2api.ServiceModel{Name: "oran-e2sm-kpm", Version: "v2"},
+}
+
+func newIndication(payload string) *e2api.SubscribeResponse {
+	return &e2api.SubscribeResponse{
+		Message: &e2api.SubscribeResponse_Indication{Indication: &e2api.Indication{Payload: []byte(payload)}},
+	}
+}
+
+func newAck(channelID string) *e2api.SubscribeResponse {
+	return &e2api.SubscribeResponse{
+		Message: &e2api.SubscribeResponse_Ack{Ack: &e2api.Acknowledgement{ChannelID: e2api.ChannelID(channelID)}},
+	}
+}
+
+// fill pushes the given indications without waiting
+func fill(t *testing.T, buffer *responseBuffer, payloads ...string) {
+	for _, payload := range payloads {
+		require.NoError(t, buffer.push(context.Background(), newIndication(payload)))
+	}
+}
+
+// drain pops the responses of the closed buffer, returning their payloads and the final error
+func drain(t *testing.T, buffer *responseBuffer) ([]string, error) {
+	var payloads []string
+	for {
+		response, err := buffer.pop(context.Background())
+		if err != nil {
+			return payloads, err
+		}
+		if response.GetAck() != nil {
+			payloads = append(payloads, string(response.GetAck().ChannelID))
+		} else {
+			payloads = append(payloads, string(response.GetIndication().Payload))
+		}
+	}
+}
+
+func droppedIndications(policy OverflowPolicy) float64 {
+	return testutil.ToFloat64(droppedIndicationsTotal.WithLabelValues("e2-buffer", "oran-e2sm-kpm", "v2", string(policy)))
+}
+
+func TestBufferBlock(t *testing.T) {
+	buffer := newResponseBuffer(testBufferHeaders, 2, Block)
+	fill(t, buffer, "1", "2")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, buffer.push(ctx, newIndication("3")))
+
+	pushCh := make(chan error, 1)
+	go func() {
+		pushCh <- buffer.push(context.Background(), newIndication("3"))
+	}()
+	response, err := buffer.pop(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []byte("1"), response.GetIndication().Payload)
+	assert.NoError(t, <-pushCh)
+
+	buffer.close(nil)
+	assert.Error(t, buffer.push(context.Background(), newIndication("4")))
+	payloads, err := drain(t, buffer)
+	assert.Equal(t, []string{"2", "3"}, payloads)
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestBufferDropOldest(t *testing.T) {
+	dropped := droppedIndications(DropOldest)
+	buffer := newResponseBuffer(testBufferHeaders, 2, DropOldest)
+	// Acknowledgements are neither counted nor dropped
+	require.NoError(t, buffer.push(context.Background(), newAck("channel-1")))
+	fill(t, buffer, "1", "2", "3", "4")
+	buffer.close(nil)
+	payloads, err := drain(t, buffer)
+	assert.Equal(t, []string{"channel-1", "3", "4"}, payloads)
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, dropped+2, droppedIndications(DropOldest))
+}
+
+func TestBufferDropNewest(t *testing.T) {
+	dropped := droppedIndications(DropNewest)
+	buffer := newResponseBuffer(testBufferHeaders, 2, DropNewest)
+	fill(t, buffer, "1", "2", "3", "4")
+	failed := status.Error(codes.Unavailable, "upstream failed")
+	buffer.close(failed)
+	payloads, err := drain(t, buffer)
+	assert.Equal(t, []string{"1", "2"}, payloads)
+	assert.Equal(t, failed, err)
+	assert.Equal(t, dropped+2, droppedIndications(DropNewest))
+}
+
+func TestBufferDisconnect(t *testing.T) {
+	disconnects := testutil.ToFloat64(slowStreamDisconnectsTotal.WithLabelValues("e2-buffer", "oran-e2sm-kpm", "v2"))
+	buffer := newResponseBuffer(testBufferHeaders, 2, Disconnect)
+	fill(t, buffer, "1", "2")
+	err := buffer.push(context.Background(), newIndication("3"))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	// The pending indications are discarded so that the app learns of the disconnection right away
+	payloads, err := drain(t, buffer)
+	assert.Empty(t, payloads)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, disconnects+1, testutil.ToFloat64(slowStreamDisconnectsTotal.WithLabelValues("e2-buffer", "oran-e2sm-kpm", "v2")))
+}
+
+func TestParseOverflowPolicy(t *testing.T) {
+	for _, policy := range []OverflowPolicy{Block, DropOldest, DropNewest, Disconnect} {
+		parsed, err := ParseOverflowPolicy(string(policy))
+		assert.NoError(t, err)
+		assert.Equal(t, policy, parsed)
+	}
+	_, err := ParseOverflowPolicy("drop-all")
+	assert.Error(t, err)
+}
@@ -0,0 +1,141 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package v1beta1
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// MasterPolicy is the policy applied to requests targeting an E2 node with no known master
+type MasterPolicy string
+
+const (
+	// FailFast fails the request immediately if the E2 node has no known master
+	FailFast MasterPolicy = "fail-fast"
+	// WaitForMaster waits for a master of the E2 node to appear until a deadline
+	WaitForMaster MasterPolicy = "wait"
+)
+
+const (
+	// masterPolicyHeader is the request header overriding the default master policy
+	masterPolicyHeader = "e2-master-policy"
+	// masterWaitTimeoutHeader is the request header overriding the default master wait timeout
+	masterWaitTimeoutHeader = "e2-master-wait-timeout"
+
+	// errorDomain is the domain of the error details returned by the proxy
+	errorDomain = "onos-proxy"
+)
+
+// Reasons reported in the error details of requests failed due to the E2 node having no known master
+const (
+	// ReasonE2NodeNotFound indicates the E2 node is not known
+	ReasonE2NodeNotFound = "E2_NODE_NOT_FOUND"
+	// ReasonMasterUnavailable indicates the E2 node is known but has no master
+	ReasonMasterUnavailable = "E2_NODE_MASTER_UNAVAILABLE"
+	// ReasonMasterWaitTimeout indicates no master of the E2 node appeared before the wait deadline
+	ReasonMasterWaitTimeout = "E2_NODE_MASTER_WAIT_TIMEOUT"
+)
+
+// ParseMasterPolicy parses the given master policy name
+func ParseMasterPolicy(policy string) (MasterPolicy, error) {
+	switch MasterPolicy(policy) {
+	case FailFast, WaitForMaster:
+		return MasterPolicy(policy), nil
+	}
+	return "", fmt.Errorf("invalid master policy %q", policy)
+}
+
+// masterPolicy returns the master policy and wait timeout for the request, applying any overrides from its headers
+func (s *ProxyServer) masterPolicy(ctx context.Context) (MasterPolicy, time.Duration, error) {
+	policy := s.options.MasterPolicy
+	timeout := s.options.MasterWaitTimeout
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(masterPolicyHeader); len(values) > 0 {
+			p, err := ParseMasterPolicy(values[0])
+			if err != nil {
+				return "", 0, status.Error(codes.InvalidArgument, err.Error())
+			}
+			policy = p
+		}
+		if values := md.Get(masterWaitTimeoutHeader); len(values) > 0 {
+			t, err := time.ParseDuration(values[0])
+			if err != nil {
+				return "", 0, status.Errorf(codes.InvalidArgument, "invalid %s header: %v", masterWaitTimeoutHeader, err)
+			} else if t < 0 {
+				return "", 0, status.Errorf(codes.InvalidArgument, "invalid %s header: negative timeout %s", masterWaitTimeoutHeader, t)
+			}
+			timeout = t
+		}
+	}
+	return policy, timeout, nil
+}
+
+// awaitMaster applies the master policy for the given E2 node, returning an error status describing
+// why the request cannot be forwarded if the node has no known master
+func (s *ProxyServer) awaitMaster(ctx context.Context, nodeID string) error {
+	policy, timeout, err := s.masterPolicy(ctx)
+	if err != nil {
+		return err
+	}
+
+	if policy == FailFast {
+		if _, ok := s.instances.E2TMaster(nodeID); ok {
+			return nil
+		}
+		if !s.instances.E2NodeExists(nodeID) {
+			return masterError(codes.NotFound, ReasonE2NodeNotFound, policy, nodeID, "E2 node %s not found", nodeID)
+		}
+		return masterError(codes.Unavailable, ReasonMasterUnavailable, policy, nodeID, "E2 node %s has no master", nodeID)
+	}
+
+	waitCtx, cancel := context.WithCancel(ctx)
+	if timeout > 0 {
+		waitCtx, cancel = context.WithTimeout(ctx, timeout)
+	}
+	defer cancel()
+
+	mastershipCh := s.instances.WatchE2TMasters(waitCtx)
+	for {
+		if _, ok := s.instances.E2TMaster(nodeID); ok {
+			return nil
+		}
+		log.Debugf("Waiting for a master of E2 node %s", nodeID)
+		select {
+		case <-mastershipCh:
+		case <-waitCtx.Done():
+			if err := ctx.Err(); err != nil {
+				return status.FromContextError(err).Err()
+			}
+			if !s.instances.E2NodeExists(nodeID) {
+				return masterError(codes.NotFound, ReasonMasterWaitTimeout, policy, nodeID, "E2 node %s not found after waiting %s", nodeID, timeout)
+			}
+			return masterError(codes.Unavailable, ReasonMasterWaitTimeout, policy, nodeID, "E2 node %s has no master after waiting %s", nodeID, timeout)
+		}
+	}
+}
+
+// masterError creates an error status with details describing the master policy failure
+func masterError(code codes.Code, reason string, policy MasterPolicy, nodeID string, format string, args ...interface{}) error {
+	st := status.Newf(code, format, args...)
+	detailed, err := st.WithDetails(&errdetails.ErrorInfo{
+		Reason: reason,
+		Domain: errorDomain,
+		Metadata: map[string]string{
+			e2NodeIDHeader:     nodeID,
+			masterPolicyHeader: string(policy),
+		},
+	})
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
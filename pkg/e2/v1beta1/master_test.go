@@ -0,0 +1,135 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package v1beta1
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type testInstances struct {
+	masters   map[string]string
+	nodes     map[string]bool
+	listeners []chan struct{}
+	mu        sync.Mutex
+}
+
+func newTestInstances() *testInstances {
+	return &testInstances{
+		masters: make(map[string]string),
+		nodes:   make(map[string]bool),
+	}
+}
+
+func (i *testInstances) E2TAddresses() []string {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	var addresses []string
+	for _, address := range i.masters {
+		addresses = append(addresses, address)
+	}
+	return addresses
+}
+
+func (i *testInstances) E2TMaster(nodeID string) (string, bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	address, ok := i.masters[nodeID]
+	return address, ok
+}
+
+func (i *testInstances) E2NodeExists(nodeID string) bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.nodes[nodeID]
+}
+
+func (i *testInstances) WatchE2TMasters(ctx context.Context) <-chan struct{} {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	ch := make(chan struct{}, 1)
+	i.listeners = append(i.listeners, ch)
+	return ch
+}
+
+func (i *testInstances) WatchState(ctx context.Context) <-chan struct{} {
+	return i.WatchE2TMasters(ctx)
+}
+
+func (i *testInstances) setMaster(nodeID string, address string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.nodes[nodeID] = true
+	i.masters[nodeID] = address
+	for _, ch := range i.listeners {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func reasonOf(t *testing.T, err error) string {
+	st, ok := status.FromError(err)
+	assert.True(t, ok)
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			return info.Reason
+		}
+	}
+	return ""
+}
+
+func TestFailFast(t *testing.T) {
+	instances := newTestInstances()
+	server := &ProxyServer{instances: instances, options: Options{MasterPolicy: FailFast}}
+
+	err := server.awaitMaster(context.Background(), "e2-1")
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	assert.Equal(t, ReasonE2NodeNotFound, reasonOf(t, err))
+
+	instances.nodes["e2-1"] = true
+	err = server.awaitMaster(context.Background(), "e2-1")
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, ReasonMasterUnavailable, reasonOf(t, err))
+
+	instances.setMaster("e2-1", "e2t-1:5150")
+	assert.NoError(t, server.awaitMaster(context.Background(), "e2-1"))
+}
+
+func TestWaitForMaster(t *testing.T) {
+	instances := newTestInstances()
+	server := &ProxyServer{instances: instances, options: Options{MasterPolicy: WaitForMaster, MasterWaitTimeout: 10 * time.Millisecond}}
+
+	err := server.awaitMaster(context.Background(), "e2-1")
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	assert.Equal(t, ReasonMasterWaitTimeout, reasonOf(t, err))
+
+	// The per-request headers override the default policy
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(masterWaitTimeoutHeader, "5s"))
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		instances.setMaster("e2-1", "e2t-1:5150")
+	}()
+	assert.NoError(t, server.awaitMaster(ctx, "e2-1"))
+
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs(masterPolicyHeader, "fail-fast"))
+	assert.Equal(t, codes.NotFound, status.Code(server.awaitMaster(ctx, "e2-2")))
+
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs(masterPolicyHeader, "retry"))
+	assert.Equal(t, codes.InvalidArgument, status.Code(server.awaitMaster(ctx, "e2-2")))
+
+	// Negative timeouts are invalid rather than waiting until the request deadline
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs(masterWaitTimeoutHeader, "-1s"))
+	assert.Equal(t, codes.InvalidArgument, status.Code(server.awaitMaster(ctx, "e2-2")))
+}
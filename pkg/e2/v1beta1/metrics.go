@@ -0,0 +1,221 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package v1beta1
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	e2api "github.com/onosproject/onos-api/go/onos/e2t/e2/v1beta1"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	metricsNamespace = "onos_proxy"
+	metricsSubsystem = "e2"
+
+	// indicationRateWindow is the number of whole seconds over which the indication rate is averaged
+	indicationRateWindow = 10
+)
+
+var (
+	streamLabels  = []string{"e2_node_id", "service_model_name", "service_model_version", "code"}
+	requestLabels = append([]string{"method"}, streamLabels...)
+)
+
+var (
+	requestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "requests_total",
+		Help:      "Total number of proxied E2 requests",
+	}, requestLabels)
+
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "request_duration_seconds",
+		Help:      "Duration of proxied E2 Control and Unsubscribe requests",
+		Buckets:   prometheus.DefBuckets,
+	}, requestLabels)
+
+	// Subscription streams last from seconds to days, so their buckets range from a second to about 18 hours
+	subscriptionStreamDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "subscription_stream_duration_seconds",
+		Help:      "Lifetime of proxied E2 Subscribe streams",
+		Buckets:   prometheus.ExponentialBuckets(1, 4, 9),
+	}, streamLabels)
+
+	subscriptionStreams = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "subscription_streams",
+		Help:      "Number of active subscription streams",
+	})
+
+	sharedSubscriptions = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "shared_subscriptions",
+		Help:      "Number of E2T subscriptions shared by multiplexed subscription streams",
+	})
+
+	sharedSubscribers = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "shared_subscribers",
+		Help:      "Number of multiplexed subscription streams attached to shared subscriptions",
+	})
+
+	indicationsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "indications_total",
+		Help:      "Total number of indications forwarded to apps",
+	}, []string{"e2_node_id", "service_model_name", "service_model_version"})
+
+	droppedIndicationsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "dropped_indications_total",
+		Help:      "Total number of indications dropped for apps not keeping up with their subscription streams",
+	}, []string{"e2_node_id", "service_model_name", "service_model_version", "policy"})
+
+	slowStreamDisconnectsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "slow_stream_disconnects_total",
+		Help:      "Total number of subscription streams disconnected for not keeping up with their indications",
+	}, []string{"e2_node_id", "service_model_name", "service_model_version"})
+
+	orphanedSubscriptionsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "orphaned_subscriptions_total",
+		Help:      "Total number of subscriptions deleted from E2T after their app streams were cancelled without unsubscribing",
+	})
+
+	indications = newRateMeter(indicationRateWindow)
+
+	indicationRate = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "indications_per_second",
+		Help:      "Number of indications forwarded to apps per second, averaged over the last 10 seconds",
+	}, func() float64 {
+		return indications.rate(time.Now())
+	})
+)
+
+func init() {
+	prometheus.MustRegister(requestsTotal, requestDuration, subscriptionStreamDuration, subscriptionStreams, sharedSubscriptions,
+		sharedSubscribers, indicationsTotal, indicationRate, droppedIndicationsTotal, slowStreamDisconnectsTotal,
+		orphanedSubscriptionsTotal)
+}
+
+// observeRequest records the outcome and duration of a proxied request; the duration of a Subscribe request is
+// the lifetime of its stream
+func observeRequest(method string, headers e2api.RequestHeaders, start time.Time, err error) {
+	labels := prometheus.Labels{
+		"method":                method,
+		"e2_node_id":            string(headers.E2NodeID),
+		"service_model_name":    string(headers.ServiceModel.Name),
+		"service_model_version": string(headers.ServiceModel.Version),
+		"code":                  errorCode(err).String(),
+	}
+	requestsTotal.With(labels).Inc()
+	duration := time.Since(start).Seconds()
+	if method == "Subscribe" {
+		delete(labels, "method")
+		subscriptionStreamDuration.With(labels).Observe(duration)
+	} else {
+		requestDuration.With(labels).Observe(duration)
+	}
+}
+
+// observeIndication records an indication forwarded to an app
+func observeIndication(headers e2api.RequestHeaders) {
+	indicationsTotal.WithLabelValues(
+		string(headers.E2NodeID),
+		string(headers.ServiceModel.Name),
+		string(headers.ServiceModel.Version)).Inc()
+	indications.add(time.Now())
+}
+
+// observeDroppedIndication records an indication dropped by the given overflow policy
+func observeDroppedIndication(headers e2api.RequestHeaders, policy OverflowPolicy) {
+	droppedIndicationsTotal.WithLabelValues(
+		string(headers.E2NodeID),
+		string(headers.ServiceModel.Name),
+		string(headers.ServiceModel.Version),
+		string(policy)).Inc()
+}
+
+// observeDisconnect records a subscription stream disconnected for not keeping up with its indications
+func observeDisconnect(headers e2api.RequestHeaders) {
+	slowStreamDisconnectsTotal.WithLabelValues(
+		string(headers.E2NodeID),
+		string(headers.ServiceModel.Name),
+		string(headers.ServiceModel.Version)).Inc()
+}
+
+// errorCode returns the gRPC status code of the given error, mapping context errors to their status codes
+func errorCode(err error) codes.Code {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Code()
+	}
+	return status.Code(err)
+}
+
+// rateMeter counts events in per-second buckets to compute an average rate over a sliding window
+type rateMeter struct {
+	buckets []rateBucket
+	mu      sync.Mutex
+}
+
+type rateBucket struct {
+	second int64
+	count  uint64
+}
+
+func newRateMeter(window int) *rateMeter {
+	return &rateMeter{
+		buckets: make([]rateBucket, window+1),
+	}
+}
+
+// add counts an event at the given time
+func (m *rateMeter) add(t time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	second := t.Unix()
+	bucket := &m.buckets[second%int64(len(m.buckets))]
+	if bucket.second != second {
+		bucket.second = second
+		bucket.count = 0
+	}
+	bucket.count++
+}
+
+// rate returns the average number of events per second over the window of whole seconds preceding the given time
+func (m *rateMeter) rate(t time.Time) float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := t.Unix()
+	window := int64(len(m.buckets) - 1)
+	var count uint64
+	for _, bucket := range m.buckets {
+		if bucket.second < now && bucket.second >= now-window {
+			count += bucket.count
+		}
+	}
+	return float64(count) / float64(window)
+}
@@ -0,0 +1,71 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package v1beta1
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	e2api "github.com/onosproject/onos-api/go/onos/e2t/e2/v1beta1"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestRateMeter(t *testing.T) {
+	meter := newRateMeter(10)
+	start := time.Unix(1000, 0)
+	for i := 0; i < 20; i++ {
+		meter.add(start)
+		meter.add(start.Add(time.Second))
+	}
+	// Events in the current second are not counted until the second is complete
+	assert.Equal(t, 2.0, meter.rate(start.Add(time.Second)))
+	assert.Equal(t, 4.0, meter.rate(start.Add(2*time.Second)))
+	assert.Equal(t, 2.0, meter.rate(start.Add(11*time.Second)))
+	assert.Equal(t, 0.0, meter.rate(start.Add(20*time.Second)))
+}
+
+func TestRequestMetrics(t *testing.T) {
+	server := &ProxyServer{instances: newTestInstances(), options: Options{MasterPolicy: FailFast}}
+	headers := e2api.RequestHeaders{
+		E2NodeID: "e2-metrics",
+		ServiceModel: e2api.ServiceModel{
+			Name:    "oran-e2sm-kpm",
+			Version: "v2",
+		},
+	}
+	// The series of the test are deleted first so that it can be repeated
+	requestsTotal.DeleteLabelValues("Control", "e2-metrics", "oran-e2sm-kpm", "v2", codes.NotFound.String())
+	requestDuration.DeleteLabelValues("Control", "e2-metrics", "oran-e2sm-kpm", "v2", codes.NotFound.String())
+	subscriptionStreamDuration.DeleteLabelValues("e2-metrics", "oran-e2sm-kpm", "v2", codes.OK.String())
+
+	_, err := server.Control(context.Background(), &e2api.ControlRequest{Headers: headers})
+	assert.Error(t, err)
+
+	counter := requestsTotal.WithLabelValues("Control", "e2-metrics", "oran-e2sm-kpm", "v2", codes.NotFound.String())
+	assert.Equal(t, 1.0, testutil.ToFloat64(counter))
+	assert.Equal(t, uint64(1), sampleCount(t, requestDuration.WithLabelValues("Control", "e2-metrics", "oran-e2sm-kpm", "v2", codes.NotFound.String())))
+
+	// The lifetime of subscription streams is observed separately from the request durations
+	observeRequest("Subscribe", headers, time.Now().Add(-2*time.Hour), nil)
+	stream := subscriptionStreamDuration.WithLabelValues("e2-metrics", "oran-e2sm-kpm", "v2", codes.OK.String())
+	assert.Equal(t, uint64(1), sampleCount(t, stream))
+	assert.Equal(t, uint64(0), sampleCount(t, requestDuration.WithLabelValues("Subscribe", "e2-metrics", "oran-e2sm-kpm", "v2", codes.OK.String())))
+
+	assert.Equal(t, codes.Canceled, errorCode(context.Canceled))
+	assert.Equal(t, codes.OK, errorCode(nil))
+}
+
+// sampleCount returns the number of observations of the given histogram
+func sampleCount(t *testing.T, observer prometheus.Observer) uint64 {
+	metric := &dto.Metric{}
+	require.NoError(t, observer.(prometheus.Metric).Write(metric))
+	return metric.GetHistogram().GetSampleCount()
+}
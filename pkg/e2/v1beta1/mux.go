@@ -0,0 +1,327 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package v1beta1
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"sync"
+
+	e2api "github.com/onosproject/onos-api/go/onos/e2t/e2/v1beta1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// sharedTransactionIDPrefix prefixes the transaction IDs of the shared subscriptions in E2T
+	sharedTransactionIDPrefix = "onos-proxy-"
+)
+
+func newSubscriptionMux(proxy *ProxyServer) *subscriptionMux {
+	return &subscriptionMux{
+		proxy:        proxy,
+		groups:       make(map[string]*subscriptionGroup),
+		running:      make(map[string]*subscriptionGroup),
+		transactions: make(map[string]bool),
+	}
+}
+
+// subscriptionMux shares a single E2T subscription among the identical subscribe requests of apps, i.e. those
+// for the same E2 node, service model, encoding and subscription spec. The shared subscription is owned by the
+// proxy: it is created when the first subscriber arrives and deleted from E2T when the last one leaves, either
+// by closing its stream or by unsubscribing.
+type subscriptionMux struct {
+	proxy *ProxyServer
+	// groups are the shared subscriptions presently accepting subscribers, by key
+	groups map[string]*subscriptionGroup
+	// running are the latest shared subscriptions of each key which may not yet have been deleted from E2T
+	running map[string]*subscriptionGroup
+	// transactions are the transactions of the app requests multiplexed by the proxy until they are unsubscribed,
+	// which outlive the shared subscriptions so that the apps can unsubscribe after closing their streams
+	transactions map[string]bool
+	mu           sync.Mutex
+}
+
+// subscriptionGroup is a shared subscription and its present subscribers
+type subscriptionGroup struct {
+	key     string
+	request *e2api.SubscribeRequest
+	// entry is the subscription in the registry of forwarded subscriptions
+	entry *registryEntry
+	// subscribers are the streams presently receiving the responses of the subscription
+	subscribers map[*subscriber]bool
+	// transactions are the transactions of the app requests attached to the subscription, which are
+	// unsubscribed locally
+	transactions map[string]bool
+	// ack is the last acknowledgement of the subscription, passed to subscribers joining after it
+	ack    *e2api.SubscribeResponse
+	closed bool
+	cancel context.CancelFunc
+	// done is closed once the subscription has been deleted from E2T
+	done chan struct{}
+	mu   sync.Mutex
+}
+
+// subscriber is an app stream receiving the responses of a shared subscription
+type subscriber struct {
+	ctx         context.Context
+	transaction string
+	buffer      *responseBuffer
+}
+
+// subscribe passes the responses of the shared subscription matching the given request to the app's stream
+// until the stream is done, the app unsubscribes or the shared subscription fails
+func (m *subscriptionMux) subscribe(request *e2api.SubscribeRequest, server e2api.SubscriptionService_SubscribeServer) error {
+	key, err := subscriptionKey(request)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid subscription: %v", err)
+	}
+	// A blocked subscriber would hold up the shared subscription for all of the others, so slow subscribers
+	// are disconnected instead
+	policy := m.proxy.options.OverflowPolicy
+	if policy == "" || policy == Block {
+		policy = Disconnect
+	}
+	member := &subscriber{
+		ctx:         server.Context(),
+		transaction: requestKey(request),
+		buffer:      newResponseBuffer(request.Headers, m.proxy.options.BufferSize, policy),
+	}
+	group := m.join(key, request, member)
+	defer m.leave(group, member)
+	return m.proxy.send(server, request, member.buffer)
+}
+
+// unsubscribe detaches the app streams of the given unsubscribe request from the shared subscriptions they
+// belong to, returning false if the request's transaction was not multiplexed
+func (m *subscriptionMux) unsubscribe(request *e2api.UnsubscribeRequest) bool {
+	transaction := transactionKey(request.Headers.AppID, request.Headers.AppInstanceID, request.TransactionID)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.transactions[transaction] {
+		return false
+	}
+	delete(m.transactions, transaction)
+	for _, group := range m.groups {
+		group.mu.Lock()
+		if group.transactions[transaction] {
+			delete(group.transactions, transaction)
+			for member := range group.subscribers {
+				if member.transaction == transaction {
+					// The stream ends once the responses already buffered for it have been sent
+					member.buffer.close(nil)
+				}
+			}
+			log.Infof("Detached transaction %s from shared subscription %s", request.TransactionID, group.request.TransactionID)
+		}
+		group.mu.Unlock()
+	}
+	return true
+}
+
+// join adds the subscriber to the shared subscription with the given key, creating it if necessary
+func (m *subscriptionMux) join(key string, request *e2api.SubscribeRequest, member *subscriber) *subscriptionGroup {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	group, ok := m.groups[key]
+	if !ok {
+		previous := m.running[key]
+		ctx, cancel := context.WithCancel(context.Background())
+		group = &subscriptionGroup{
+			key: key,
+			request: &e2api.SubscribeRequest{
+				Headers:            request.Headers,
+				TransactionID:      sharedTransactionID(key),
+				Subscription:       request.Subscription,
+				TransactionTimeout: request.TransactionTimeout,
+			},
+			subscribers:  make(map[*subscriber]bool),
+			transactions: make(map[string]bool),
+			cancel:       cancel,
+			done:         make(chan struct{}),
+		}
+		group.entry = m.proxy.registry.add(group.request, true)
+		m.groups[key] = group
+		m.running[key] = group
+		log.Infof("Sharing subscription %s for SubscribeRequest %+v", group.request.TransactionID, request)
+		go m.run(ctx, group, previous)
+	} else {
+		m.proxy.registry.attach(group.entry)
+	}
+
+	group.mu.Lock()
+	defer group.mu.Unlock()
+	group.subscribers[member] = true
+	group.transactions[member.transaction] = true
+	m.transactions[member.transaction] = true
+	if group.ack != nil {
+		_ = member.buffer.push(member.ctx, group.ack)
+	}
+	sharedSubscribers.Inc()
+	return group
+}
+
+// leave removes the subscriber from the shared subscription, which is deleted if it was the last one
+func (m *subscriptionMux) leave(group *subscriptionGroup, member *subscriber) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	group.mu.Lock()
+	defer group.mu.Unlock()
+	if _, ok := group.subscribers[member]; !ok {
+		return
+	}
+	delete(group.subscribers, member)
+	if !group.hasTransaction(member.transaction) {
+		delete(group.transactions, member.transaction)
+	}
+	sharedSubscribers.Dec()
+	m.proxy.registry.detach(group.entry, false)
+	if len(group.subscribers) == 0 && !group.closed {
+		group.closed = true
+		delete(m.groups, group.key)
+		group.cancel()
+	}
+}
+
+// run maintains the shared subscription until it fails or its last subscriber leaves, then deletes it from E2T
+func (m *subscriptionMux) run(ctx context.Context, group *subscriptionGroup, previous *subscriptionGroup) {
+	defer close(group.done)
+	sharedSubscriptions.Inc()
+	defer sharedSubscriptions.Dec()
+
+	// A previous subscription with the same key, and thus transaction ID, must be deleted before it is reused
+	var err error
+	subscribed := false
+	if previous != nil {
+		select {
+		case <-previous.done:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+	}
+	if err == nil {
+		subscribed = true
+		err = m.proxy.forward(ctx, group.request, group.broadcast)
+	}
+
+	m.mu.Lock()
+	if m.groups[group.key] == group {
+		delete(m.groups, group.key)
+	}
+	m.mu.Unlock()
+	group.mu.Lock()
+	group.closed = true
+	for member := range group.subscribers {
+		member.buffer.close(err)
+	}
+	group.mu.Unlock()
+	group.cancel()
+
+	// The subscription is kept in the registry while it may remain in E2T, from which it is deleted when the
+	// proxy restarts otherwise
+	if !subscribed {
+		m.proxy.registry.remove(group.entry)
+	} else if err = m.delete(group.request); err == nil || status.Code(err) == codes.NotFound {
+		m.proxy.registry.remove(group.entry)
+	} else {
+		log.Warnf("Failed to delete shared subscription %s: %s", group.request.TransactionID, err)
+	}
+	m.mu.Lock()
+	if m.running[group.key] == group {
+		delete(m.running, group.key)
+	}
+	m.mu.Unlock()
+}
+
+// delete deletes the shared subscription of the given request from E2T
+func (m *subscriptionMux) delete(request *e2api.SubscribeRequest) error {
+	if m.proxy.options.Recording != nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), unsubscribeTimeout)
+	defer cancel()
+	return unsubscribe(ctx, m.proxy.conn, request)
+}
+
+// wait waits until the running shared subscriptions have been deleted from E2T, or the context is done
+func (m *subscriptionMux) wait(ctx context.Context) error {
+	m.mu.Lock()
+	groups := make([]*subscriptionGroup, 0, len(m.running))
+	for _, group := range m.running {
+		groups = append(groups, group)
+	}
+	m.mu.Unlock()
+	for _, group := range groups {
+		select {
+		case <-group.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// broadcast passes a response of the shared subscription to all of its subscribers, applying the overflow
+// policy to each of them
+func (g *subscriptionGroup) broadcast(response *e2api.SubscribeResponse) error {
+	g.mu.Lock()
+	if response.GetAck() != nil {
+		g.ack = response
+	} else {
+		g.entry.observeIndication()
+	}
+	members := make([]*subscriber, 0, len(g.subscribers))
+	for member := range g.subscribers {
+		members = append(members, member)
+	}
+	g.mu.Unlock()
+
+	for _, member := range members {
+		// Subscribers which are closed or disconnected by the overflow policy are about to leave
+		_ = member.buffer.push(member.ctx, response)
+	}
+	return nil
+}
+
+// hasTransaction returns whether any present subscriber belongs to the given transaction
+func (g *subscriptionGroup) hasTransaction(transaction string) bool {
+	for member := range g.subscribers {
+		if member.transaction == transaction {
+			return true
+		}
+	}
+	return false
+}
+
+// subscriptionKey returns the key identifying the subscriptions identical to the given request's
+func subscriptionKey(request *e2api.SubscribeRequest) (string, error) {
+	// The generated marshalling encodes the fields in order, and so is canonical for the spec, which has no maps
+	spec, err := request.Subscription.Marshal()
+	if err != nil {
+		return "", err
+	}
+	headers := request.Headers
+	return fmt.Sprintf("%s/%s:%s/%s/%x", headers.E2NodeID, headers.ServiceModel.Name, headers.ServiceModel.Version,
+		headers.Encoding, sha256.Sum256(spec)), nil
+}
+
+// sharedTransactionID returns the transaction ID of the shared subscription with the given key, which is
+// stable so that the subscription can be recognized in E2T
+func sharedTransactionID(key string) e2api.TransactionID {
+	sum := sha256.Sum256([]byte(key))
+	return e2api.TransactionID(fmt.Sprintf("%s%x", sharedTransactionIDPrefix, sum[:8]))
+}
+
+// transactionKey returns the key identifying the given transaction of an app instance, which E2T tells apart
+// from the same transaction of the app's other instances
+func transactionKey(appID e2api.AppID, appInstanceID e2api.AppInstanceID, transactionID e2api.TransactionID) string {
+	return fmt.Sprintf("%s/%s/%s", appID, appInstanceID, transactionID)
+}
+
+// requestKey returns the key identifying the transaction of the given request
+func requestKey(request *e2api.SubscribeRequest) string {
+	return transactionKey(request.Headers.AppID, request.Headers.AppInstanceID, request.TransactionID)
+}
@@ -0,0 +1,225 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package v1beta1
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	e2api "github.com/onosproject/onos-api/go/onos/e2t/e2/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func newSubscribeRequest(transactionID string, trigger string) *e2api.SubscribeRequest {
+	return &e2api.SubscribeRequest{
+		Headers: e2api.RequestHeaders{
+			AppID:        "test-app",
+			E2NodeID:     "e2-1",
+			ServiceModel: e2api.ServiceModel{Name: "oran-e2sm-kpm", Version: "v2"},
+		},
+		TransactionID: e2api.TransactionID(transactionID),
+		Subscription: e2api.SubscriptionSpec{
+			EventTrigger: e2api.EventTrigger{Payload: []byte(trigger)},
+		},
+	}
+}
+
+// subscribe opens a subscription stream via the proxy and returns it along with the channel ID acknowledged
+func (e *testEnv) subscribe(t *testing.T, ctx context.Context, request *e2api.SubscribeRequest) (e2api.SubscriptionService_SubscribeClient, e2api.ChannelID) {
+	stream, err := e2api.NewSubscriptionServiceClient(e.conn).Subscribe(ctx, request)
+	require.NoError(t, err)
+	response, err := stream.Recv()
+	require.NoError(t, err)
+	require.NotNil(t, response.GetAck())
+	return stream, response.GetAck().ChannelID
+}
+
+func assertIndication(t *testing.T, stream e2api.SubscriptionService_SubscribeClient, payload string) {
+	response, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, []byte(payload), response.GetIndication().GetPayload())
+}
+
+func TestSubscriptionMultiplexing(t *testing.T) {
+	env := newTestEnvWithOptions(t, Options{MasterPolicy: WaitForMaster, MultiplexSubscriptions: true}, "e2t-1")
+	env.topo.SetMaster("e2-1", "e2t-1")
+	e2t := env.e2ts["e2t-1"]
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	ctx1, cancel1 := context.WithCancel(ctx)
+	defer cancel1()
+	stream1, channel1 := env.subscribe(t, ctx1, newSubscribeRequest("sub-1", "trigger-1"))
+	// Identical requests share the subscription, and are acknowledged with its channel even once established
+	stream2, channel2 := env.subscribe(t, ctx, newSubscribeRequest("sub-2", "trigger-1"))
+	assert.Equal(t, channel1, channel2)
+	// Requests with another spec get their own subscription
+	stream3, channel3 := env.subscribe(t, ctx, newSubscribeRequest("sub-3", "trigger-2"))
+	assert.NotEqual(t, channel1, channel3)
+
+	subscribes := e2t.Subscribes()
+	require.Len(t, subscribes, 2)
+	shared := subscribes[0]
+	assert.Equal(t, e2api.TransactionID(sharedTransactionIDPrefix), shared.TransactionID[:len(sharedTransactionIDPrefix)])
+	assert.Equal(t, e2api.AppID("test-app"), shared.Headers.AppID)
+
+	assert.Equal(t, 2, e2t.Indicate("e2-1", e2api.Indication{Payload: []byte("1")}))
+	assertIndication(t, stream1, "1")
+	assertIndication(t, stream2, "1")
+	assertIndication(t, stream3, "1")
+
+	// The subscription is kept while subscribers remain
+	cancel1()
+	assert.Eventually(t, func() bool {
+		return e2t.Indicate("e2-1", e2api.Indication{Payload: []byte("2")}) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	assertIndication(t, stream2, "2")
+	assertIndication(t, stream3, "2")
+	assert.Empty(t, e2t.Unsubscribes())
+
+	// Unsubscribing the last subscriber ends its stream and deletes the subscription from E2T
+	_, err := e2api.NewSubscriptionServiceClient(env.conn).Unsubscribe(ctx, &e2api.UnsubscribeRequest{
+		Headers:       e2api.RequestHeaders{AppID: "test-app", E2NodeID: "e2-1"},
+		TransactionID: "sub-2",
+	})
+	require.NoError(t, err)
+	_, err = stream2.Recv()
+	assert.Equal(t, io.EOF, err)
+	assert.Eventually(t, func() bool {
+		unsubscribes := e2t.Unsubscribes()
+		return len(unsubscribes) == 1 && unsubscribes[0].TransactionID == shared.TransactionID
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 1, e2t.Streams())
+
+	// A new subscriber recreates the subscription
+	stream1, _ = env.subscribe(t, ctx, newSubscribeRequest("sub-1", "trigger-1"))
+	assert.Len(t, e2t.Subscribes(), 3)
+	assert.Equal(t, shared.TransactionID, e2t.Subscribes()[2].TransactionID)
+	assert.Equal(t, 2, e2t.Indicate("e2-1", e2api.Indication{Payload: []byte("3")}))
+	assertIndication(t, stream1, "3")
+	assertIndication(t, stream3, "3")
+}
+
+func TestSubscriptionKey(t *testing.T) {
+	key, err := subscriptionKey(newSubscribeRequest("sub-1", "trigger-1"))
+	require.NoError(t, err)
+	other, err := subscriptionKey(newSubscribeRequest("sub-2", "trigger-1"))
+	require.NoError(t, err)
+	assert.Equal(t, key, other)
+
+	for _, request := range []*e2api.SubscribeRequest{
+		newSubscribeRequest("sub-1", "trigger-2"),
+		func() *e2api.SubscribeRequest {
+			request := newSubscribeRequest("sub-1", "trigger-1")
+			request.Headers.E2NodeID = "e2-2"
+			return request
+		}(),
+		func() *e2api.SubscribeRequest {
+			request := newSubscribeRequest("sub-1", "trigger-1")
+			request.Headers.ServiceModel.Version = "v3"
+			return request
+		}(),
+		func() *e2api.SubscribeRequest {
+			request := newSubscribeRequest("sub-1", "trigger-1")
+			request.Headers.Encoding = e2api.Encoding_ASN1_PER
+			return request
+		}(),
+		func() *e2api.SubscribeRequest {
+			request := newSubscribeRequest("sub-1", "trigger-1")
+			request.Subscription.Actions = []e2api.Action{{ID: 1, Type: e2api.ActionType_ACTION_TYPE_REPORT}}
+			return request
+		}(),
+	} {
+		other, err := subscriptionKey(request)
+		require.NoError(t, err)
+		assert.NotEqual(t, key, other)
+	}
+}
+
+func TestSlowSubscriber(t *testing.T) {
+	// Slow subscribers are disconnected rather than blocked, including with the default policy
+	for _, policy := range []OverflowPolicy{"", Block, Disconnect} {
+		t.Run(string(policy), func(t *testing.T) {
+			testSlowSubscriber(t, policy)
+		})
+	}
+}
+
+func testSlowSubscriber(t *testing.T, policy OverflowPolicy) {
+	env := newTestEnvWithOptions(t, Options{
+		MasterPolicy:           WaitForMaster,
+		MultiplexSubscriptions: true,
+		BufferSize:             1,
+		OverflowPolicy:         policy,
+	}, "e2t-1")
+	env.topo.SetMaster("e2-1", "e2t-1")
+	e2t := env.e2ts["e2t-1"]
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	fast, _ := env.subscribe(t, ctx, newSubscribeRequest("sub-1", "trigger-1"))
+	// The flow control window of the slow subscriber is fixed so that it fills up once it stops reading
+	slowConn, err := grpc.Dial(testProxyAddress, append(env.network.DialOptions(),
+		grpc.WithInitialWindowSize(64*1024), grpc.WithInitialConnWindowSize(64*1024))...)
+	require.NoError(t, err)
+	defer slowConn.Close()
+	slow, err := e2api.NewSubscriptionServiceClient(slowConn).Subscribe(ctx, newSubscribeRequest("sub-2", "trigger-1"))
+	require.NoError(t, err)
+	response, err := slow.Recv()
+	require.NoError(t, err)
+	require.NotNil(t, response.GetAck())
+
+	// The slow subscriber does not hold up the other one, and is disconnected once its buffer overflows
+	payload := make([]byte, 16*1024)
+	for i := 0; i < 20; i++ {
+		assert.Equal(t, 1, e2t.Indicate("e2-1", e2api.Indication{Payload: payload}))
+		response, err := fast.Recv()
+		require.NoError(t, err)
+		require.NotNil(t, response.GetIndication())
+	}
+	for {
+		_, err := slow.Recv()
+		if err != nil {
+			assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+			break
+		}
+	}
+	assert.Equal(t, 1, e2t.Streams())
+}
+
+func TestUnsubscribeClosedStream(t *testing.T) {
+	env := newTestEnvWithOptions(t, Options{MasterPolicy: WaitForMaster, MultiplexSubscriptions: true}, "e2t-1")
+	env.topo.SetMaster("e2-1", "e2t-1")
+	e2t := env.e2ts["e2t-1"]
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	streamCtx, streamCancel := context.WithCancel(ctx)
+	env.subscribe(t, streamCtx, newSubscribeRequest("sub-1", "trigger-1"))
+	shared := e2t.Subscribes()[0]
+
+	// Closing the last stream deletes the shared subscription from E2T
+	streamCancel()
+	assert.Eventually(t, func() bool {
+		return len(e2t.Unsubscribes()) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// The app's transaction is still unsubscribed by the proxy rather than in E2T, where it never existed
+	request := &e2api.UnsubscribeRequest{
+		Headers:       e2api.RequestHeaders{AppID: "test-app", E2NodeID: "e2-1"},
+		TransactionID: "sub-1",
+	}
+	_, err := e2api.NewSubscriptionServiceClient(env.conn).Unsubscribe(ctx, request)
+	require.NoError(t, err)
+	unsubscribes := e2t.Unsubscribes()
+	require.Len(t, unsubscribes, 1)
+	assert.Equal(t, shared.TransactionID, unsubscribes[0].TransactionID)
+}
@@ -6,51 +6,284 @@ package v1beta1
 
 import (
 	"context"
+	"fmt"
 	"io"
+	"sync"
+	"time"
 
 	e2api "github.com/onosproject/onos-api/go/onos/e2t/e2/v1beta1"
 	"github.com/onosproject/onos-lib-go/pkg/logging"
 	"github.com/onosproject/onos-lib-go/pkg/northbound"
+	"github.com/onosproject/onos-proxy/pkg/tracing"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 var log = logging.GetLogger()
 
-const e2NodeIDHeader = "e2-node-id"
+const (
+	e2NodeIDHeader   = "e2-node-id"
+	e2tAddressHeader = "e2t-address"
+
+	// unsubscribeTimeout is the maximum time to wait for E2T to delete a subscription the proxy unsubscribes
+	unsubscribeTimeout = 5 * time.Second
+
+	// minReconcileRetryInterval and maxReconcileRetryInterval bound the interval between attempts to
+	// reconcile the restored subscriptions with E2T
+	minReconcileRetryInterval = 100 * time.Millisecond
+	maxReconcileRetryInterval = 10 * time.Second
+)
+
+// E2TInstances provides the addresses and E2 node mastership of the E2T instances known to the proxy
+type E2TInstances interface {
+	// E2TAddresses returns the addresses of all known E2T instances
+	E2TAddresses() []string
+	// E2TMaster returns the address of the E2T instance presently mastering the given E2 node
+	E2TMaster(nodeID string) (string, bool)
+	// E2NodeExists returns whether the given E2 node is known
+	E2NodeExists(nodeID string) bool
+	// WatchE2TMasters returns a channel signalled whenever the E2 node mastership changes
+	WatchE2TMasters(ctx context.Context) <-chan struct{}
+	// WatchState returns a channel signalled whenever the E2T instances or the E2 node mastership may have changed
+	WatchState(ctx context.Context) <-chan struct{}
+}
+
+// Options are the E2 proxy service options
+type Options struct {
+	// MasterPolicy is the default policy for requests targeting E2 nodes with no known master
+	MasterPolicy MasterPolicy
+	// MasterWaitTimeout is the default maximum time to wait for a master; zero waits until the request deadline
+	MasterWaitTimeout time.Duration
+	// MultiplexSubscriptions shares a single E2T subscription among identical subscribe requests
+	MultiplexSubscriptions bool
+	// BufferSize is the number of indications buffered for each subscription stream; zero uses DefaultBufferSize
+	BufferSize int
+	// OverflowPolicy is the policy applied to indications for subscription streams whose buffer is full;
+	// empty blocks
+	OverflowPolicy OverflowPolicy
+	// OrphanTimeout is the time after which subscriptions whose app streams were cancelled without
+	// unsubscribing are deleted from E2T; zero leaves them to the apps
+	OrphanTimeout time.Duration
+	// StatePath is the path of the file the forwarded subscriptions are persisted to, so that they can be
+	// reconciled with E2T when the proxy restarts; empty disables persistence
+	StatePath string
+	// Recorder records the responses of the subscriptions forwarded to E2T, if set
+	Recorder *Recorder
+	// Recording is the recording subscriptions are served from instead of being forwarded to E2T, if set
+	Recording *Recording
+	// ReplaySpeed is the factor by which the replay of the recording is accelerated; zero replays in real time
+	ReplaySpeed float64
+}
 
 // NewProxyService creates a new E2T control and subscription proxy service
-func NewProxyService(clientConn *grpc.ClientConn) northbound.Service {
-	return &SubscriptionService{
-		conn: clientConn,
+func NewProxyService(clientConn *grpc.ClientConn, instances E2TInstances, options Options) *SubscriptionService {
+	service := &SubscriptionService{
+		conn:      clientConn,
+		instances: instances,
+		options:   options,
+		streams:   newAppStreams(),
 	}
+	service.registry = newSubscriptionRegistry(options.OrphanTimeout, func(request *e2api.SubscribeRequest) {
+		if options.Recording != nil {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), unsubscribeTimeout)
+		defer cancel()
+		_ = unsubscribe(ctx, clientConn, request)
+	})
+	if options.MultiplexSubscriptions {
+		// The multiplexer is shared by the servers of all registrations
+		service.mux = newSubscriptionMux(service.newServer())
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	service.cancel = cancel
+	if options.StatePath != "" {
+		if err := service.registry.restore(newStateStore(options.StatePath)); err != nil {
+			log.Warnf("Unable to restore subscriptions: %v", err)
+		}
+		go service.reconcile(ctx)
+	}
+	return service
 }
 
 // SubscriptionService is a Service implementation for E2 Subscription service.
 type SubscriptionService struct {
 	northbound.Service
-	conn *grpc.ClientConn
+	conn      *grpc.ClientConn
+	instances E2TInstances
+	options   Options
+	registry  *subscriptionRegistry
+	mux       *subscriptionMux
+	streams   *appStreams
+	cancel    context.CancelFunc
 }
 
 // Register registers the SubscriptionService with the gRPC server.
 func (s SubscriptionService) Register(r *grpc.Server) {
-	server := &ProxyServer{
-		conn: s.conn,
-	}
+	server := s.newServer()
 	e2api.RegisterSubscriptionServiceServer(r, server)
+	e2api.RegisterSubscriptionAdminServiceServer(r, server)
 	e2api.RegisterControlServiceServer(r, server)
 }
 
+func (s SubscriptionService) newServer() *ProxyServer {
+	return &ProxyServer{
+		conn:      s.conn,
+		instances: s.instances,
+		options:   s.options,
+		registry:  s.registry,
+		mux:       s.mux,
+		streams:   s.streams,
+	}
+}
+
+// Subscriptions returns the subscriptions forwarded to E2T by the proxy which have not been unsubscribed
+func (s *SubscriptionService) Subscriptions() []Subscription {
+	return s.registry.list()
+}
+
+// Close stops deleting orphaned subscriptions and reconciling restored ones; it is meant for shutdown, when the
+// app streams are closed by the proxy rather than by the apps
+func (s *SubscriptionService) Close() {
+	s.cancel()
+	s.registry.close()
+}
+
+// reconcile reconciles the subscriptions restored from the state of the previous run of the proxy with the
+// channels in E2T, retrying until all E2T instances can be listed
+func (s *SubscriptionService) reconcile(ctx context.Context) {
+	server := s.newServer()
+	retryInterval := minReconcileRetryInterval
+	for {
+		response, err := server.ListChannels(ctx, &e2api.ListChannelsRequest{})
+		if err == nil {
+			for _, request := range s.registry.reconcile(response.Channels) {
+				log.Infof("Deleting restored shared subscription %s", request.TransactionID)
+				ctx, cancel := context.WithTimeout(ctx, unsubscribeTimeout)
+				_ = unsubscribe(ctx, s.conn, request)
+				cancel()
+			}
+			log.Info("Reconciled restored subscriptions with E2T")
+			return
+		}
+		log.Warnf("Unable to reconcile restored subscriptions with E2T, retrying in %s: %v", retryInterval, err)
+		select {
+		case <-time.After(retryInterval):
+		case <-ctx.Done():
+			return
+		}
+		if retryInterval *= 2; retryInterval > maxReconcileRetryInterval {
+			retryInterval = maxReconcileRetryInterval
+		}
+	}
+}
+
+// CloseStreams ends the subscription streams open through the proxy, and those opened from then on, with an
+// Unavailable error once the responses already buffered for them have been sent, so that the apps resubscribe,
+// e.g. through another instance of the proxy. It is meant for shutdown, since the streams would otherwise last
+// until the apps close them. The dedicated subscriptions are kept in E2T for the apps to resume unless
+// deleteSubscriptions is set, in which case those of the closed streams are deleted. The subscriptions shared
+// by multiplexed streams are always deleted, since their last subscribers leave as the streams end.
+func (s *SubscriptionService) CloseStreams(ctx context.Context, deleteSubscriptions bool) error {
+	// The subscriptions are listed before their streams end and detach from them
+	requests := s.registry.active()
+	s.streams.close(status.Error(codes.Unavailable, "proxy shutting down"))
+	if !deleteSubscriptions || s.options.Recording != nil {
+		return nil
+	}
+	var failed int
+	for _, request := range requests {
+		// Shared subscriptions may already have been deleted once their last subscriber left
+		if err := unsubscribe(ctx, s.conn, request); err != nil && status.Code(err) != codes.NotFound {
+			failed++
+			continue
+		}
+		s.registry.removeTransaction(request.Headers.AppID, request.Headers.AppInstanceID, request.TransactionID)
+	}
+	if failed > 0 {
+		return fmt.Errorf("unable to delete %d subscriptions", failed)
+	}
+	return nil
+}
+
+// WaitSharedSubscriptions waits until the shared subscriptions of the multiplexed streams, which are deleted
+// from E2T once their last subscriber leaves, have been deleted, or the context is done. It is meant for
+// shutdown, once the streams have ended, so that the deletions complete before the E2T connection is closed.
+func (s *SubscriptionService) WaitSharedSubscriptions(ctx context.Context) error {
+	if s.mux == nil {
+		return nil
+	}
+	return s.mux.wait(ctx)
+}
+
 // ProxyServer implements the gRPC service for E2 Subscription related functions.
 type ProxyServer struct {
-	conn *grpc.ClientConn
+	conn      *grpc.ClientConn
+	instances E2TInstances
+	options   Options
+	registry  *subscriptionRegistry
+	mux       *subscriptionMux
+	streams   *appStreams
+}
+
+func newAppStreams() *appStreams {
+	return &appStreams{
+		buffers: make(map[*responseBuffer]bool),
+	}
+}
+
+// appStreams are the response buffers of the subscription streams presently open to apps
+type appStreams struct {
+	buffers map[*responseBuffer]bool
+	// err is the error the streams were closed with, if any; streams added afterwards are closed right away
+	err error
+	mu  sync.Mutex
+}
+
+// add registers the buffer of a stream, closing it if the streams are closed
+func (a *appStreams) add(buffer *responseBuffer) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.err != nil {
+		buffer.close(a.err)
+		return
+	}
+	a.buffers[buffer] = true
 }
 
-func (s *ProxyServer) Control(ctx context.Context, request *e2api.ControlRequest) (*e2api.ControlResponse, error) {
+// remove unregisters the buffer of a stream once the stream ended
+func (a *appStreams) remove(buffer *responseBuffer) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.buffers, buffer)
+}
+
+// close closes the buffers of all streams with the given error
+func (a *appStreams) close(err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.err = err
+	for buffer := range a.buffers {
+		buffer.close(err)
+	}
+}
+
+func (s *ProxyServer) Control(ctx context.Context, request *e2api.ControlRequest) (response *e2api.ControlResponse, err error) {
 	log.Debugf("ControlRequest %+v", request)
+	defer func(start time.Time) {
+		observeRequest("Control", request.Headers, start, err)
+	}(time.Now())
+	trace.SpanFromContext(ctx).SetAttributes(tracing.E2NodeIDKey.String(string(request.Headers.E2NodeID)))
+	if err = s.awaitMaster(ctx, string(request.Headers.E2NodeID)); err != nil {
+		log.Warnf("ControlRequest %+v error: %s", request, err)
+		return nil, err
+	}
 	client := e2api.NewControlServiceClient(s.conn)
 	ctx = metadata.AppendToOutgoingContext(ctx, e2NodeIDHeader, string(request.Headers.E2NodeID))
-	response, err := client.Control(ctx, request)
+	response, err = client.Control(ctx, request)
 	if err != nil {
 		log.Warnf("ControlRequest %+v error: %s", request, err)
 		return nil, err
@@ -59,11 +292,147 @@ func (s *ProxyServer) Control(ctx context.Context, request *e2api.ControlRequest
 	return response, nil
 }
 
-func (s *ProxyServer) Subscribe(request *e2api.SubscribeRequest, server e2api.SubscriptionService_SubscribeServer) error {
+// Subscribe forwards the subscription to the E2T instance mastering the target E2 node. If the mastership
+// of the node moves to another E2T instance, the same request is re-issued against the new master while
+// the app's stream is kept open. When multiplexing subscriptions, the subscription is shared with any
+// identical ones of other streams. When replaying a recording, the subscription is served from the recording.
+func (s *ProxyServer) Subscribe(request *e2api.SubscribeRequest, server e2api.SubscriptionService_SubscribeServer) (err error) {
 	log.Debugf("SubscribeRequest %+v", request)
+	subscriptionStreams.Inc()
+	defer func(start time.Time) {
+		subscriptionStreams.Dec()
+		observeRequest("Subscribe", request.Headers, start, err)
+	}(time.Now())
+	nodeID := string(request.Headers.E2NodeID)
+	trace.SpanFromContext(server.Context()).SetAttributes(tracing.E2NodeIDKey.String(nodeID))
+	if s.options.Recording == nil {
+		if err = s.awaitMaster(server.Context(), nodeID); err != nil {
+			log.Warnf("SubscribeRequest %+v error: %s", request, err)
+			return err
+		}
+	}
+	if s.mux != nil {
+		return s.mux.subscribe(request, server)
+	}
+
+	entry := s.registry.add(request, false)
+
+	// The upstream responses are buffered so that the upstream stream is not held up by a slow app, within
+	// the limits of the overflow policy
+	ctx, cancel := context.WithCancel(server.Context())
+	buffer := newResponseBuffer(request.Headers, s.options.BufferSize, s.options.OverflowPolicy)
+	var upstreamErr, pushErr error
+	var upstreamFailed bool
+	doneCh := make(chan struct{})
+	go func() {
+		defer close(doneCh)
+		upstreamErr = s.forward(ctx, request, func(response *e2api.SubscribeResponse) error {
+			if response.GetIndication() != nil {
+				entry.observeIndication()
+			}
+			pushErr = buffer.push(ctx, response)
+			return pushErr
+		})
+		// The upstream stream failed unless it was cancelled or the app's buffer overflowed
+		upstreamFailed = upstreamErr != nil && pushErr == nil && ctx.Err() == nil
+		buffer.close(upstreamErr)
+	}()
+	err = s.send(server, request, buffer)
+	// Wait for the upstream subscription stream to be cancelled if the app's stream ended first
+	cancel()
+	<-doneCh
+
+	if upstreamErr == nil {
+		// The subscription ended in E2T
+		s.registry.remove(entry)
+	} else if upstreamFailed && status.Code(upstreamErr) != codes.Unavailable {
+		// The subscription failed in E2T
+		s.registry.remove(entry)
+	}
+	// A subscription which E2T could not be reached for may remain there without app stream, as if cancelled
+	s.registry.detach(entry, server.Context().Err() != nil || upstreamFailed)
+	return err
+}
+
+// send sends the responses of the given buffer on the app's stream until the buffer is closed and drained
+func (s *ProxyServer) send(server e2api.SubscriptionService_SubscribeServer, request *e2api.SubscribeRequest, buffer *responseBuffer) error {
+	s.streams.add(buffer)
+	defer s.streams.remove(buffer)
+	for {
+		response, err := buffer.pop(server.Context())
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := server.Send(response); err != nil {
+			log.Warnf("SubscribeResponse %+v error: %s", response, err)
+			return err
+		}
+		if response.GetIndication() != nil {
+			observeIndication(request.Headers)
+		}
+	}
+}
+
+// forward subscribes to the E2T instance mastering the target E2 node and passes the responses to the given
+// function until the context is done or either fails. If the mastership of the node moves to another E2T
+// instance, the same request is re-issued against the new master. When replaying a recording, the recorded
+// responses are passed instead.
+func (s *ProxyServer) forward(ctx context.Context, request *e2api.SubscribeRequest, send func(*e2api.SubscribeResponse) error) error {
+	if s.options.Recording != nil {
+		return s.replay(ctx, request, send)
+	}
+	nodeID := string(request.Headers.E2NodeID)
+	mastershipCh := s.instances.WatchE2TMasters(ctx)
+
+	// The first attempt is routed by E2 node; subsequent ones target the new master instance directly
+	// since the balancer may not have yet picked up the mastership change
+	master, _ := s.instances.E2TMaster(nodeID)
+	upstreamCtx := metadata.AppendToOutgoingContext(ctx, e2NodeIDHeader, nodeID)
+	var opts []grpc.CallOption
+	for {
+		subCtx, cancel := context.WithCancel(upstreamCtx)
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- s.subscribe(subCtx, request, send, opts...)
+		}()
+
+		resubscribe := false
+		for !resubscribe {
+			select {
+			case err := <-errCh:
+				cancel()
+				return err
+			case _, ok := <-mastershipCh:
+				if !ok {
+					// The context is done; wait for the upstream subscription to be cancelled
+					mastershipCh = nil
+					continue
+				}
+				if newMaster, ok := s.instances.E2TMaster(nodeID); ok && newMaster != master {
+					log.Infof("E2 node %s mastership changed from %s to %s; re-issuing SubscribeRequest %+v", nodeID, master, newMaster, request)
+					master = newMaster
+					resubscribe = true
+				}
+			}
+		}
+		cancel()
+		<-errCh
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		upstreamCtx = metadata.AppendToOutgoingContext(ctx, e2NodeIDHeader, nodeID, e2tAddressHeader, master)
+		// The connection to the new master may not be ready yet if the instance was only just added
+		opts = []grpc.CallOption{grpc.WaitForReady(true)}
+	}
+}
+
+// subscribe opens an upstream subscription stream and passes its responses to the given function until either ends
+func (s *ProxyServer) subscribe(ctx context.Context, request *e2api.SubscribeRequest, send func(*e2api.SubscribeResponse) error, opts ...grpc.CallOption) error {
 	client := e2api.NewSubscriptionServiceClient(s.conn)
-	ctx := metadata.AppendToOutgoingContext(server.Context(), e2NodeIDHeader, string(request.Headers.E2NodeID))
-	clientStream, err := client.Subscribe(ctx, request)
+	clientStream, err := client.Subscribe(ctx, request, opts...)
 	if err != nil {
 		log.Warnf("SubscribeRequest %+v error: %s", request, err)
 		return err
@@ -79,19 +448,49 @@ func (s *ProxyServer) Subscribe(request *e2api.SubscribeRequest, server e2api.Su
 			return err
 		}
 		log.Debugf("SubscribeResponse %+v", response)
-		err = server.Send(response)
-		if err != nil {
-			log.Warnf("SubscribeResponse %+v error: %s", response, err)
+		if s.options.Recorder != nil {
+			s.options.Recorder.record(request, response)
+		}
+		if err := send(response); err != nil {
 			return err
 		}
 	}
 }
 
-func (s *ProxyServer) Unsubscribe(ctx context.Context, request *e2api.UnsubscribeRequest) (*e2api.UnsubscribeResponse, error) {
+func (s *ProxyServer) Unsubscribe(ctx context.Context, request *e2api.UnsubscribeRequest) (response *e2api.UnsubscribeResponse, err error) {
 	log.Debugf("UnsubscribeRequest %+v", request)
+	defer func(start time.Time) {
+		observeRequest("Unsubscribe", request.Headers, start, err)
+	}(time.Now())
+	trace.SpanFromContext(ctx).SetAttributes(tracing.E2NodeIDKey.String(string(request.Headers.E2NodeID)))
+	if s.mux != nil && s.mux.unsubscribe(request) {
+		// The subscription is shared and deleted from E2T only once its last subscriber leaves
+		response = &e2api.UnsubscribeResponse{
+			Headers: e2api.ResponseHeaders{Encoding: request.Headers.Encoding},
+		}
+		log.Debugf("UnsubscribeResponse %+v", response)
+		return response, nil
+	}
+	if s.options.Recording != nil {
+		// Replayed subscriptions exist in the proxy only
+		s.registry.removeTransaction(request.Headers.AppID, request.Headers.AppInstanceID, request.TransactionID)
+		response = &e2api.UnsubscribeResponse{
+			Headers: e2api.ResponseHeaders{Encoding: request.Headers.Encoding},
+		}
+		log.Debugf("UnsubscribeResponse %+v", response)
+		return response, nil
+	}
+	if err = s.awaitMaster(ctx, string(request.Headers.E2NodeID)); err != nil {
+		log.Warnf("UnsubscribeRequest %+v error: %s", request, err)
+		return nil, err
+	}
 	client := e2api.NewSubscriptionServiceClient(s.conn)
 	ctx = metadata.AppendToOutgoingContext(ctx, e2NodeIDHeader, string(request.Headers.E2NodeID))
-	response, err := client.Unsubscribe(ctx, request)
+	response, err = client.Unsubscribe(ctx, request)
+	if err == nil || status.Code(err) == codes.NotFound {
+		// The subscription no longer exists in E2T
+		s.registry.removeTransaction(request.Headers.AppID, request.Headers.AppInstanceID, request.TransactionID)
+	}
 	if err != nil {
 		log.Warnf("UnsubscribeRequest %+v error: %s", request, err)
 		return nil, err
@@ -99,3 +498,19 @@ func (s *ProxyServer) Unsubscribe(ctx context.Context, request *e2api.Unsubscrib
 	log.Debugf("UnsubscribeResponse %+v", response)
 	return response, nil
 }
+
+// unsubscribe deletes the subscription of the given subscribe request from E2T
+func unsubscribe(ctx context.Context, conn *grpc.ClientConn, request *e2api.SubscribeRequest) error {
+	unsubscribeRequest := &e2api.UnsubscribeRequest{
+		Headers:       request.Headers,
+		TransactionID: request.TransactionID,
+	}
+	log.Infof("Unsubscribing %+v", unsubscribeRequest)
+	client := e2api.NewSubscriptionServiceClient(conn)
+	ctx = metadata.AppendToOutgoingContext(ctx, e2NodeIDHeader, string(request.Headers.E2NodeID))
+	if _, err := client.Unsubscribe(ctx, unsubscribeRequest); err != nil {
+		log.Warnf("UnsubscribeRequest %+v error: %s", unsubscribeRequest, err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package v1beta1
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	e2api "github.com/onosproject/onos-api/go/onos/e2t/e2/v1beta1"
+	"github.com/onosproject/onos-api/go/onos/topo"
+	"github.com/onosproject/onos-proxy/pkg/e2/v1beta1/balancer"
+	"github.com/onosproject/onos-proxy/pkg/harness"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+const (
+	testTopoAddress  = "onos-topo:5150"
+	testProxyAddress = "onos-proxy:5151"
+	testE2TPort      = 5150
+)
+
+// testEnv is a proxy wired to a fake topo service and a set of fake E2T instances
+type testEnv struct {
+	network *harness.Network
+	topo    *harness.TopoServer
+	e2ts    map[topo.ID]*harness.E2TServer
+	builder *balancer.ResolverBuilder
+	e2tConn *grpc.ClientConn
+	service *SubscriptionService
+	conn    *grpc.ClientConn
+}
+
+func newTestEnv(t *testing.T, e2tIDs ...topo.ID) *testEnv {
+	return newTestEnvWithOptions(t, Options{MasterPolicy: WaitForMaster, MasterWaitTimeout: 5 * time.Second}, e2tIDs...)
+}
+
+func newTestEnvWithOptions(t *testing.T, options Options, e2tIDs ...topo.ID) *testEnv {
+	env := &testEnv{
+		network: harness.NewNetwork(),
+		topo:    harness.NewTopoServer(),
+		e2ts:    make(map[topo.ID]*harness.E2TServer),
+		builder: balancer.NewResolverBuilder(testTopoAddress),
+	}
+	t.Cleanup(env.network.Close)
+	env.network.Serve(testTopoAddress, env.topo.Register)
+	for _, id := range e2tIDs {
+		e2t := harness.NewE2TServer(fmt.Sprintf("%s:%d", id, testE2TPort))
+		env.network.Serve(e2t.Address(), e2t.Register)
+		env.topo.AddE2T(id, string(id), testE2TPort)
+		env.e2ts[id] = e2t
+	}
+
+	var err error
+	env.e2tConn, err = grpc.Dial(fmt.Sprintf("%s:///%s", balancer.ResolverName, "onos-e2t:5150"),
+		append(env.network.DialOptions(), grpc.WithResolvers(env.builder))...)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = env.e2tConn.Close()
+	})
+	env.service = NewProxyService(env.e2tConn, env.builder, options)
+	env.network.Serve(testProxyAddress, env.service.Register)
+
+	env.conn, err = grpc.Dial(testProxyAddress, env.network.DialOptions()...)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = env.conn.Close()
+	})
+	return env
+}
+
+// control sends a control request for the given E2 node via the proxy, returning the address of the E2T
+// instance which served it
+func (e *testEnv) control(nodeID string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	response, err := e2api.NewControlServiceClient(e.conn).Control(ctx, &e2api.ControlRequest{
+		Headers: e2api.RequestHeaders{E2NodeID: e2api.E2NodeID(nodeID)},
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(response.Outcome.Payload), nil
+}
+
+func assertRoutedTo(t *testing.T, env *testEnv, nodeID string, address string) {
+	served, err := env.control(nodeID)
+	assert.NoError(t, err)
+	assert.Equal(t, address, served)
+}
+
+func assertReroutedTo(t *testing.T, env *testEnv, nodeID string, address string) {
+	assert.Eventually(t, func() bool {
+		served, err := env.control(nodeID)
+		return err == nil && served == address
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestControlRouting(t *testing.T) {
+	env := newTestEnv(t, "e2t-1", "e2t-2")
+	env.topo.SetMaster("e2-1", "e2t-1")
+	env.topo.SetMaster("e2-2", "e2t-2")
+
+	assertRoutedTo(t, env, "e2-1", "e2t-1:5150")
+	assertRoutedTo(t, env, "e2-2", "e2t-2:5150")
+	assert.Len(t, env.e2ts["e2t-1"].Controls(), 1)
+	assert.Len(t, env.e2ts["e2t-2"].Controls(), 1)
+
+	// Requests are rerouted once the new master has been picked up by the balancer
+	env.topo.SetMaster("e2-1", "e2t-2")
+	assertReroutedTo(t, env, "e2-1", "e2t-2:5150")
+}
+
+func TestControlWaitForMaster(t *testing.T) {
+	env := newTestEnv(t, "e2t-1")
+	env.topo.AddE2Node("e2-1")
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		env.topo.SetMaster("e2-1", "e2t-1")
+	}()
+	assertRoutedTo(t, env, "e2-1", "e2t-1:5150")
+}
+
+func TestSubscribeFailover(t *testing.T) {
+	env := newTestEnv(t, "e2t-1", "e2t-2")
+	env.topo.SetMaster("e2-1", "e2t-1")
+	e2t1, e2t2 := env.e2ts["e2t-1"], env.e2ts["e2t-2"]
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	request := &e2api.SubscribeRequest{
+		Headers:       e2api.RequestHeaders{E2NodeID: "e2-1"},
+		TransactionID: "sub-1",
+	}
+	stream, err := e2api.NewSubscriptionServiceClient(env.conn).Subscribe(ctx, request)
+	require.NoError(t, err)
+
+	response, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, e2t1.ChannelID(request), response.GetAck().ChannelID)
+	assert.Equal(t, 1, e2t1.Indicate("e2-1", e2api.Indication{Payload: []byte("1")}))
+	response, err = stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("1"), response.GetIndication().Payload)
+
+	// The subscription is re-issued against the new master without closing the app's stream
+	env.topo.SetMaster("e2-1", "e2t-2")
+	response, err = stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, e2t2.ChannelID(request), response.GetAck().ChannelID)
+	assert.Eventually(t, func() bool {
+		return e2t1.Streams() == 0
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 1, e2t2.Indicate("e2-1", e2api.Indication{Payload: []byte("2")}))
+	response, err = stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("2"), response.GetIndication().Payload)
+
+	cancel()
+	assert.Eventually(t, func() bool {
+		return e2t2.Streams() == 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestSubscribeFailoverToNewInstance(t *testing.T) {
+	env := newTestEnv(t, "e2t-1")
+	env.topo.SetMaster("e2-1", "e2t-1")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	request := &e2api.SubscribeRequest{
+		Headers:       e2api.RequestHeaders{E2NodeID: "e2-1"},
+		TransactionID: "sub-1",
+	}
+	stream, err := e2api.NewSubscriptionServiceClient(env.conn).Subscribe(ctx, request)
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.NoError(t, err)
+
+	// The mastership moves to an instance added at the same time, whose connection is not ready yet
+	e2t := harness.NewE2TServer(fmt.Sprintf("e2t-2:%d", testE2TPort))
+	env.network.Serve(e2t.Address(), e2t.Register)
+	env.topo.AddE2T("e2t-2", "e2t-2", testE2TPort)
+	env.topo.SetMaster("e2-1", "e2t-2")
+	response, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, e2t.ChannelID(request), response.GetAck().ChannelID)
+}
+
+func TestResolverTopoRestart(t *testing.T) {
+	env := newTestEnv(t, "e2t-1", "e2t-2")
+	env.topo.SetMaster("e2-1", "e2t-1")
+	assertRoutedTo(t, env, "e2-1", "e2t-1:5150")
+
+	// Mastership changes while the topo watch is broken are picked up on resynchronization
+	assert.Eventually(t, func() bool {
+		return env.topo.Watches() == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	env.topo.BreakWatches()
+	env.topo.SetMaster("e2-1", "e2t-2")
+	assertReroutedTo(t, env, "e2-1", "e2t-2:5150")
+}
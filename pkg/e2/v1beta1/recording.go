@@ -0,0 +1,212 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package v1beta1
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	e2api "github.com/onosproject/onos-api/go/onos/e2t/e2/v1beta1"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	recordingapi "github.com/onosproject/onos-proxy/api/recording/v1"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// maxRecordSize is the maximum size of a record accepted when loading a recording
+const maxRecordSize = 64 * 1024 * 1024
+
+// NewRecorder creates a recorder appending to the recording at the given path, which is created if necessary
+func NewRecorder(path string) (*Recorder, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open recording: %v", err)
+	}
+	return &Recorder{file: file}, nil
+}
+
+// Recorder records the responses of the subscriptions forwarded to E2T, along with the time they were received
+// and the subscribe request of their subscription, as length-delimited recording.v1.Record messages
+type Recorder struct {
+	file   *os.File
+	closed bool
+	mu     sync.Mutex
+}
+
+// record appends the given response of the subscription of the given request to the recording
+func (r *Recorder) record(request *e2api.SubscribeRequest, response *e2api.SubscribeResponse) {
+	if err := r.write(request, response); err != nil {
+		log.Warnf("Unable to record SubscribeResponse %+v: %v", response, err)
+	}
+}
+
+func (r *Recorder) write(request *e2api.SubscribeRequest, response *e2api.SubscribeResponse) error {
+	record := &recordingapi.Record{
+		Time: timestamppb.Now(),
+	}
+	var err error
+	if record.Request, err = request.Marshal(); err != nil {
+		return err
+	}
+	if record.Response, err = response.Marshal(); err != nil {
+		return err
+	}
+	bytes, err := proto.Marshal(record)
+	if err != nil {
+		return err
+	}
+	// The length and record are written at once so that records appended to the file by several proxies are
+	// not interleaved
+	bytes = append(protowire.AppendVarint(nil, uint64(len(bytes))), bytes...)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return fmt.Errorf("recorder closed")
+	}
+	_, err = r.file.Write(bytes)
+	return err
+}
+
+// Close stops recording and closes the recording
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	return r.file.Close()
+}
+
+// LoadRecording loads the recording at the given path for replay. Of the app transactions recorded for
+// identical subscriptions, e.g. by several apps, only the first one is replayed, including the streams it was
+// resubscribed on after E2T failovers.
+func LoadRecording(path string) (*Recording, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open recording: %v", err)
+	}
+	defer file.Close()
+
+	recording := &Recording{
+		subscriptions: make(map[string][]recordedResponse),
+	}
+	// transactions are the app transactions replayed for each subscription
+	transactions := make(map[string]string)
+	reader := bufio.NewReader(file)
+	for i := 0; ; i++ {
+		record, err := readRecord(reader)
+		if err == io.EOF {
+			return recording, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("invalid record %d of recording %s: %v", i, path, err)
+		}
+		request := &e2api.SubscribeRequest{}
+		if err := request.Unmarshal(record.Request); err != nil {
+			return nil, fmt.Errorf("invalid request in record %d of recording %s: %v", i, path, err)
+		}
+		response := &e2api.SubscribeResponse{}
+		if err := response.Unmarshal(record.Response); err != nil {
+			return nil, fmt.Errorf("invalid response in record %d of recording %s: %v", i, path, err)
+		}
+		key, err := subscriptionKey(request)
+		if err != nil {
+			return nil, fmt.Errorf("invalid request in record %d of recording %s: %v", i, path, err)
+		}
+		if transaction, ok := transactions[key]; !ok {
+			transactions[key] = requestKey(request)
+		} else if requestKey(request) != transaction {
+			continue
+		}
+		recording.subscriptions[key] = append(recording.subscriptions[key], recordedResponse{
+			time:     record.Time.AsTime(),
+			response: response,
+		})
+	}
+}
+
+// readRecord reads the next length-delimited record; it returns io.EOF if there are no more records
+func readRecord(reader *bufio.Reader) (*recordingapi.Record, error) {
+	size, err := binary.ReadUvarint(reader)
+	if err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+	if size > maxRecordSize {
+		return nil, fmt.Errorf("record of %d bytes exceeds the maximum of %d", size, maxRecordSize)
+	}
+	bytes := make([]byte, size)
+	if _, err := io.ReadFull(reader, bytes); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	record := &recordingapi.Record{}
+	if err := proto.Unmarshal(bytes, record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// Recording is a recording of subscription responses, replayed to the subscribe requests identical to those
+// they were recorded for, i.e. for the same E2 node, service model, encoding and subscription spec
+type Recording struct {
+	subscriptions map[string][]recordedResponse
+}
+
+// recordedResponse is a response in a recording and the time it was received
+type recordedResponse struct {
+	time     time.Time
+	response *e2api.SubscribeResponse
+}
+
+// replay passes the responses recorded for the subscription of the given request to the given function, at
+// their recorded intervals divided by the replay speed. Acknowledgements begin each recorded E2T stream, and so
+// are passed right away rather than after the time elapsed since the previous stream.
+func (s *ProxyServer) replay(ctx context.Context, request *e2api.SubscribeRequest, send func(*e2api.SubscribeResponse) error) error {
+	key, err := subscriptionKey(request)
+	if err != nil {
+		return errors.Status(errors.NewInvalid("invalid subscription: %v", err)).Err()
+	}
+	responses := s.options.Recording.subscriptions[key]
+	if len(responses) == 0 {
+		return errors.Status(errors.NewNotFound("no recorded responses for the subscription of E2 node %s", request.Headers.E2NodeID)).Err()
+	}
+	speed := s.options.ReplaySpeed
+	if speed <= 0 {
+		speed = 1
+	}
+
+	log.Infof("Replaying %d recorded responses for SubscribeRequest %+v", len(responses), request)
+	for i, recorded := range responses {
+		if i > 0 && recorded.response.GetAck() == nil {
+			delay := time.Duration(float64(recorded.time.Sub(responses[i-1].time)) / speed)
+			if delay > 0 {
+				timer := time.NewTimer(delay)
+				select {
+				case <-timer.C:
+				case <-ctx.Done():
+					timer.Stop()
+					return ctx.Err()
+				}
+			}
+		}
+		if err := send(recorded.response); err != nil {
+			return err
+		}
+	}
+	return nil
+}
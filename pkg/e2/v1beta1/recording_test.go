@@ -0,0 +1,173 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package v1beta1
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	e2api "github.com/onosproject/onos-api/go/onos/e2t/e2/v1beta1"
+	recordingapi "github.com/onosproject/onos-proxy/api/recording/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// writeRecording appends the given responses of the subscription of the given request, received at the given
+// offsets from now, to a recording
+func writeRecording(t *testing.T, path string, request *e2api.SubscribeRequest, offsets []time.Duration, responses ...*e2api.SubscribeResponse) {
+	requestBytes, err := request.Marshal()
+	require.NoError(t, err)
+	now := time.Now()
+	var bytes []byte
+	for i, response := range responses {
+		responseBytes, err := response.Marshal()
+		require.NoError(t, err)
+		record, err := proto.Marshal(&recordingapi.Record{
+			Time:     timestamppb.New(now.Add(offsets[i])),
+			Request:  requestBytes,
+			Response: responseBytes,
+		})
+		require.NoError(t, err)
+		bytes = protowire.AppendVarint(bytes, uint64(len(record)))
+		bytes = append(bytes, record...)
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	require.NoError(t, err)
+	_, err = file.Write(bytes)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recording.bin")
+	recorder, err := NewRecorder(path)
+	require.NoError(t, err)
+	env := newTestEnvWithOptions(t, Options{MasterPolicy: WaitForMaster, Recorder: recorder}, "e2t-1")
+	env.topo.SetMaster("e2-1", "e2t-1")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	recordCtx, cancelRecord := context.WithCancel(ctx)
+	stream, _ := env.subscribe(t, recordCtx, newSubscribeRequest("sub-1", "trigger-1"))
+	assert.Equal(t, 1, env.e2ts["e2t-1"].Indicate("e2-1", e2api.Indication{Payload: []byte("1")}))
+	assertIndication(t, stream, "1")
+	assert.Equal(t, 1, env.e2ts["e2t-1"].Indicate("e2-1", e2api.Indication{Payload: []byte("2")}))
+	assertIndication(t, stream, "2")
+	cancelRecord()
+	require.NoError(t, recorder.Close())
+
+	recording, err := LoadRecording(path)
+	require.NoError(t, err)
+	replay := newTestEnvWithOptions(t, Options{MasterPolicy: WaitForMaster, Recording: recording, ReplaySpeed: 100}, "e2t-1")
+	e2t := replay.e2ts["e2t-1"]
+
+	// The recorded subscription is replayed to another app's identical request, with no E2 node master, and
+	// ends once the recording does
+	request := newSubscribeRequest("sub-2", "trigger-1")
+	request.Headers.AppID = "other-app"
+	stream, _ = replay.subscribe(t, ctx, request)
+	assertIndication(t, stream, "1")
+	assertIndication(t, stream, "2")
+	_, err = stream.Recv()
+	assert.Equal(t, io.EOF, err)
+	assert.Empty(t, e2t.Subscribes())
+
+	// Subscriptions which were not recorded are not found
+	stream, err = e2api.NewSubscriptionServiceClient(replay.conn).Subscribe(ctx, newSubscribeRequest("sub-3", "trigger-2"))
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	// and unsubscribing is answered by the proxy
+	_, err = e2api.NewSubscriptionServiceClient(replay.conn).Unsubscribe(ctx, &e2api.UnsubscribeRequest{
+		Headers:       request.Headers,
+		TransactionID: request.TransactionID,
+	})
+	require.NoError(t, err)
+	assert.Empty(t, e2t.Unsubscribes())
+}
+
+func TestReplaySpeed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recording.bin")
+	request := newSubscribeRequest("sub-1", "trigger-1")
+	writeRecording(t, path, request, []time.Duration{0, time.Second, 2 * time.Second},
+		&e2api.SubscribeResponse{Message: &e2api.SubscribeResponse_Ack{Ack: &e2api.Acknowledgement{ChannelID: "channel-1"}}},
+		&e2api.SubscribeResponse{Message: &e2api.SubscribeResponse_Indication{Indication: &e2api.Indication{Payload: []byte("1")}}},
+		&e2api.SubscribeResponse{Message: &e2api.SubscribeResponse_Indication{Indication: &e2api.Indication{Payload: []byte("2")}}})
+	recording, err := LoadRecording(path)
+	require.NoError(t, err)
+	env := newTestEnvWithOptions(t, Options{MasterPolicy: WaitForMaster, Recording: recording, ReplaySpeed: 10})
+
+	// The acknowledgement is replayed right away and the indications at a tenth of their recorded intervals
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	start := time.Now()
+	stream, channelID := env.subscribe(t, ctx, request)
+	assert.Equal(t, e2api.ChannelID("channel-1"), channelID)
+	assertIndication(t, stream, "1")
+	assertIndication(t, stream, "2")
+	elapsed := time.Since(start)
+	assert.GreaterOrEqual(t, elapsed, 200*time.Millisecond)
+	assert.Less(t, elapsed, 2*time.Second)
+}
+
+func TestReplayOneTransaction(t *testing.T) {
+	// The identical subscriptions of two apps are recorded concurrently, and the first one is resubscribed on
+	// another E2T stream after a failover
+	path := filepath.Join(t.TempDir(), "recording.bin")
+	request := newSubscribeRequest("sub-1", "trigger-1")
+	otherRequest := newSubscribeRequest("sub-1", "trigger-1")
+	otherRequest.Headers.AppID = "other-app"
+	ack := func(channelID e2api.ChannelID) *e2api.SubscribeResponse {
+		return &e2api.SubscribeResponse{Message: &e2api.SubscribeResponse_Ack{Ack: &e2api.Acknowledgement{ChannelID: channelID}}}
+	}
+	indication := func(payload string) *e2api.SubscribeResponse {
+		return &e2api.SubscribeResponse{Message: &e2api.SubscribeResponse_Indication{Indication: &e2api.Indication{Payload: []byte(payload)}}}
+	}
+	writeRecording(t, path, request, []time.Duration{0, 0}, ack("channel-1"), indication("1"))
+	writeRecording(t, path, otherRequest, []time.Duration{0, 0}, ack("channel-2"), indication("other-1"))
+	writeRecording(t, path, request, []time.Duration{0, 0}, ack("channel-3"), indication("2"))
+	writeRecording(t, path, otherRequest, []time.Duration{0}, indication("other-2"))
+	recording, err := LoadRecording(path)
+	require.NoError(t, err)
+	env := newTestEnvWithOptions(t, Options{MasterPolicy: WaitForMaster, Recording: recording, ReplaySpeed: 100})
+
+	// Only the first app transaction is replayed, across the failover
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	stream, channelID := env.subscribe(t, ctx, newSubscribeRequest("sub-2", "trigger-1"))
+	assert.Equal(t, e2api.ChannelID("channel-1"), channelID)
+	assertIndication(t, stream, "1")
+	response, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, e2api.ChannelID("channel-3"), response.GetAck().GetChannelID())
+	assertIndication(t, stream, "2")
+	_, err = stream.Recv()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestLoadRecordingErrors(t *testing.T) {
+	_, err := LoadRecording(filepath.Join(t.TempDir(), "missing.bin"))
+	assert.Error(t, err)
+
+	// A truncated record is invalid
+	path := filepath.Join(t.TempDir(), "recording.bin")
+	writeRecording(t, path, newSubscribeRequest("sub-1", "trigger-1"), []time.Duration{0},
+		&e2api.SubscribeResponse{Message: &e2api.SubscribeResponse_Ack{Ack: &e2api.Acknowledgement{ChannelID: "channel-1"}}})
+	bytes, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, bytes[:len(bytes)-1], 0644))
+	_, err = LoadRecording(path)
+	assert.Error(t, err)
+}
@@ -0,0 +1,362 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package v1beta1
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	e2api "github.com/onosproject/onos-api/go/onos/e2t/e2/v1beta1"
+)
+
+// Subscription is a subscription forwarded to E2T by the proxy
+type Subscription struct {
+	// Headers are the headers of the subscribe request
+	Headers e2api.RequestHeaders
+	// TransactionID is the transaction ID of the subscribe request
+	TransactionID e2api.TransactionID
+	// StartTime is the time the subscription was first forwarded
+	StartTime time.Time
+	// Indications is the number of indications received from E2T for the subscription
+	Indications uint64
+	// Streams is the number of app streams presently receiving the subscription; zero if the app's stream
+	// ended without unsubscribing
+	Streams int
+	// Shared indicates the subscription is shared by multiplexed streams and owned by the proxy
+	Shared bool
+	// Status is the status of the subscription
+	Status SubscriptionStatus
+}
+
+func newSubscriptionRegistry(orphanTimeout time.Duration, unsubscribe func(*e2api.SubscribeRequest)) *subscriptionRegistry {
+	return &subscriptionRegistry{
+		entries:       make(map[string]*registryEntry),
+		orphanTimeout: orphanTimeout,
+		unsubscribe:   unsubscribe,
+	}
+}
+
+// subscriptionRegistry keeps track of the subscriptions forwarded to E2T, by app instance and transaction ID,
+// from the time they are first forwarded until they are unsubscribed or end in E2T. Subscriptions whose app
+// streams are cancelled without unsubscribing are deleted from E2T after the orphan timeout, unless it is zero.
+// The registry may be persisted to a state store, from which it is restored when the proxy restarts.
+type subscriptionRegistry struct {
+	entries       map[string]*registryEntry
+	orphanTimeout time.Duration
+	unsubscribe   func(*e2api.SubscribeRequest)
+	closed        bool
+	store         *stateStore
+	// changed indicates the persisted state of the registry changed while it was locked
+	changed bool
+	version uint64
+	mu      sync.Mutex
+}
+
+// registryEntry is a subscription in the registry
+type registryEntry struct {
+	request     *e2api.SubscribeRequest
+	shared      bool
+	startTime   time.Time
+	indications uint64
+	streams     int
+	// restored indicates the subscription was restored from the state store and is yet to be reconciled
+	restored bool
+	cleanup  *time.Timer
+}
+
+// status returns the status of the subscription; the registry must be locked
+func (e *registryEntry) status() SubscriptionStatus {
+	switch {
+	case e.streams > 0:
+		return SubscriptionActive
+	case e.restored:
+		return SubscriptionRestored
+	}
+	return SubscriptionDetached
+}
+
+// key returns the key of the subscription in the registry
+func (e *registryEntry) key() string {
+	return requestKey(e.request)
+}
+
+// observeIndication counts an indication received for the subscription
+func (e *registryEntry) observeIndication() {
+	atomic.AddUint64(&e.indications, 1)
+}
+
+// add registers an app stream of the given subscription. A dedicated subscription already in the registry, e.g.
+// resumed by the app after its stream broke, is attached to; a shared subscription always replaces any
+// previous one, which is being deleted.
+func (r *subscriptionRegistry) add(request *e2api.SubscribeRequest, shared bool) *registryEntry {
+	key := requestKey(request)
+	r.mu.Lock()
+	defer r.unlock()
+	if entry, ok := r.entries[key]; ok && !shared && !entry.shared {
+		r.attachLocked(entry)
+		return entry
+	}
+	entry := &registryEntry{
+		request:   request,
+		shared:    shared,
+		startTime: time.Now(),
+		streams:   1,
+	}
+	r.entries[key] = entry
+	r.changed = true
+	return entry
+}
+
+// attach registers another app stream of the subscription
+func (r *subscriptionRegistry) attach(entry *registryEntry) {
+	r.mu.Lock()
+	defer r.unlock()
+	r.attachLocked(entry)
+}
+
+func (r *subscriptionRegistry) attachLocked(entry *registryEntry) {
+	entry.streams++
+	if entry.streams == 1 {
+		entry.restored = false
+		r.changed = true
+	}
+	if entry.cleanup != nil {
+		entry.cleanup.Stop()
+		entry.cleanup = nil
+	}
+}
+
+// detach unregisters an app stream of the subscription. If it was the last one and was cancelled by the app,
+// or cut off from E2T, the subscription is deleted once the orphan timeout expires, unless the app resumes or
+// unsubscribes it first.
+func (r *subscriptionRegistry) detach(entry *registryEntry, orphaned bool) {
+	r.mu.Lock()
+	defer r.unlock()
+	entry.streams--
+	if entry.streams > 0 || r.entries[entry.key()] != entry {
+		return
+	}
+	r.changed = true
+	if orphaned {
+		r.orphanLocked(entry)
+	}
+}
+
+// orphanLocked schedules the deletion of the orphaned subscription, unless the orphan timeout is zero; the
+// registry must be locked
+func (r *subscriptionRegistry) orphanLocked(entry *registryEntry) {
+	if r.orphanTimeout == 0 || r.closed {
+		return
+	}
+	entry.cleanup = time.AfterFunc(r.orphanTimeout, func() {
+		r.cleanupOrphan(entry)
+	})
+}
+
+// cleanupOrphan deletes the subscription from E2T if it is still orphaned
+func (r *subscriptionRegistry) cleanupOrphan(entry *registryEntry) {
+	key := entry.key()
+	r.mu.Lock()
+	if r.closed || r.entries[key] != entry || entry.streams > 0 {
+		r.unlock()
+		return
+	}
+	r.removeLocked(key, entry)
+	r.unlock()
+	log.Infof("Unsubscribing orphaned SubscribeRequest %+v: no app stream for %s", entry.request, r.orphanTimeout)
+	orphanedSubscriptionsTotal.Inc()
+	r.unsubscribe(entry.request)
+}
+
+// remove removes the subscription from the registry, e.g. once it ended in E2T
+func (r *subscriptionRegistry) remove(entry *registryEntry) {
+	key := entry.key()
+	r.mu.Lock()
+	defer r.unlock()
+	if r.entries[key] == entry {
+		r.removeLocked(key, entry)
+	}
+}
+
+// removeTransaction removes the subscription of the given app transaction from the registry, e.g. once
+// the app unsubscribed it
+func (r *subscriptionRegistry) removeTransaction(appID e2api.AppID, appInstanceID e2api.AppInstanceID, transactionID e2api.TransactionID) {
+	key := transactionKey(appID, appInstanceID, transactionID)
+	r.mu.Lock()
+	defer r.unlock()
+	if entry, ok := r.entries[key]; ok {
+		r.removeLocked(key, entry)
+	}
+}
+
+func (r *subscriptionRegistry) removeLocked(key string, entry *registryEntry) {
+	if entry.cleanup != nil {
+		entry.cleanup.Stop()
+		entry.cleanup = nil
+	}
+	delete(r.entries, key)
+	r.changed = true
+}
+
+// list returns the subscriptions in the registry, ordered by E2 node, app, app instance and transaction ID
+func (r *subscriptionRegistry) list() []Subscription {
+	r.mu.Lock()
+	subscriptions := make([]Subscription, 0, len(r.entries))
+	for _, entry := range r.entries {
+		subscriptions = append(subscriptions, Subscription{
+			Headers:       entry.request.Headers,
+			TransactionID: entry.request.TransactionID,
+			StartTime:     entry.startTime,
+			Indications:   atomic.LoadUint64(&entry.indications),
+			Streams:       entry.streams,
+			Shared:        entry.shared,
+			Status:        entry.status(),
+		})
+	}
+	r.mu.Unlock()
+	sort.Slice(subscriptions, func(i, j int) bool {
+		a, b := subscriptions[i], subscriptions[j]
+		if a.Headers.E2NodeID != b.Headers.E2NodeID {
+			return a.Headers.E2NodeID < b.Headers.E2NodeID
+		}
+		if a.Headers.AppID != b.Headers.AppID {
+			return a.Headers.AppID < b.Headers.AppID
+		}
+		if a.Headers.AppInstanceID != b.Headers.AppInstanceID {
+			return a.Headers.AppInstanceID < b.Headers.AppInstanceID
+		}
+		return a.TransactionID < b.TransactionID
+	})
+	return subscriptions
+}
+
+// active returns the requests of the subscriptions presently received by app streams
+func (r *subscriptionRegistry) active() []*e2api.SubscribeRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var requests []*e2api.SubscribeRequest
+	for _, entry := range r.entries {
+		if entry.streams > 0 {
+			requests = append(requests, entry.request)
+		}
+	}
+	return requests
+}
+
+// close stops deleting orphaned subscriptions, so that those of the app streams closed on shutdown are kept
+func (r *subscriptionRegistry) close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	for _, entry := range r.entries {
+		if entry.cleanup != nil {
+			entry.cleanup.Stop()
+			entry.cleanup = nil
+		}
+	}
+}
+
+// unlock unlocks the registry, saving it to the state store first if it changed
+func (r *subscriptionRegistry) unlock() {
+	if !r.changed || r.store == nil {
+		r.changed = false
+		r.mu.Unlock()
+		return
+	}
+	r.changed = false
+	r.version++
+	version := r.version
+	state := &subscriptionState{
+		Subscriptions: make([]persistedSubscription, 0, len(r.entries)),
+	}
+	for _, entry := range r.entries {
+		request, err := entry.request.Marshal()
+		if err != nil {
+			log.Warnf("Unable to persist SubscribeRequest %+v: %v", entry.request, err)
+			continue
+		}
+		state.Subscriptions = append(state.Subscriptions, persistedSubscription{
+			AppID:         entry.request.Headers.AppID,
+			AppInstanceID: entry.request.Headers.AppInstanceID,
+			TransactionID: entry.request.TransactionID,
+			E2NodeID:      entry.request.Headers.E2NodeID,
+			Shared:        entry.shared,
+			StartTime:     entry.startTime,
+			Status:        entry.status(),
+			Request:       request,
+		})
+	}
+	r.mu.Unlock()
+	// The state is saved outside the lock; the store skips states superseded by concurrent saves
+	r.store.save(version, state)
+}
+
+// restore restores the subscriptions persisted in the given store, which the registry is saved to from then
+// on. The restored subscriptions have no app streams until they are resumed, and are kept until they are
+// reconciled with the subscriptions in E2T.
+func (r *subscriptionRegistry) restore(store *stateStore) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.store = store
+	state, err := store.load()
+	if err != nil {
+		return err
+	}
+	for _, subscription := range state.Subscriptions {
+		request := &e2api.SubscribeRequest{}
+		if err := request.Unmarshal(subscription.Request); err != nil {
+			log.Warnf("Unable to restore subscription %s of app %s: %v", subscription.TransactionID, subscription.AppID, err)
+			continue
+		}
+		key := requestKey(request)
+		if _, ok := r.entries[key]; ok {
+			continue
+		}
+		r.entries[key] = &registryEntry{
+			request:   request,
+			shared:    subscription.Shared,
+			startTime: subscription.StartTime,
+			restored:  true,
+		}
+		log.Infof("Restored %s subscription %s of app %s for E2 node %s", subscription.Status,
+			subscription.TransactionID, subscription.AppID, subscription.E2NodeID)
+	}
+	return nil
+}
+
+// reconcile reconciles the restored subscriptions which have not been resumed with the given channels of
+// the subscriptions in E2T. Those no longer in E2T are removed. Of the others, the shared subscriptions, whose
+// subscribers have all left, are removed and returned to be deleted from E2T, while the dedicated ones are kept
+// for their apps to resume, and deleted once the orphan timeout expires unless it is zero.
+func (r *subscriptionRegistry) reconcile(channels []e2api.Channel) []*e2api.SubscribeRequest {
+	subscribed := make(map[string]bool)
+	for _, channel := range channels {
+		subscribed[transactionKey(channel.AppID, channel.AppInstanceID, channel.TransactionID)] = true
+	}
+
+	r.mu.Lock()
+	defer r.unlock()
+	var deleted []*e2api.SubscribeRequest
+	for key, entry := range r.entries {
+		if !entry.restored {
+			continue
+		}
+		entry.restored = false
+		r.changed = true
+		switch {
+		case !subscribed[key]:
+			log.Infof("Restored subscription %s of app %s is no longer in E2T", entry.request.TransactionID, entry.request.Headers.AppID)
+			r.removeLocked(key, entry)
+		case entry.shared:
+			r.removeLocked(key, entry)
+			deleted = append(deleted, entry.request)
+		default:
+			r.orphanLocked(entry)
+		}
+	}
+	return deleted
+}
@@ -0,0 +1,338 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package v1beta1
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	e2api "github.com/onosproject/onos-api/go/onos/e2t/e2/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newTestRegistry returns a registry reporting the subscriptions it unsubscribes on the returned channel
+func newTestRegistry(orphanTimeout time.Duration) (*subscriptionRegistry, <-chan *e2api.SubscribeRequest) {
+	ch := make(chan *e2api.SubscribeRequest, 10)
+	return newSubscriptionRegistry(orphanTimeout, func(request *e2api.SubscribeRequest) {
+		ch <- request
+	}), ch
+}
+
+func TestRegistryList(t *testing.T) {
+	registry, _ := newTestRegistry(0)
+	request2 := newSubscribeRequest("sub-2", "trigger-1")
+	request1 := newSubscribeRequest("sub-1", "trigger-1")
+	entry2 := registry.add(request2, false)
+	entry1 := registry.add(request1, false)
+	entry2.observeIndication()
+	entry2.observeIndication()
+
+	subscriptions := registry.list()
+	require.Len(t, subscriptions, 2)
+	assert.Equal(t, e2api.TransactionID("sub-1"), subscriptions[0].TransactionID)
+	assert.Equal(t, e2api.TransactionID("sub-2"), subscriptions[1].TransactionID)
+	assert.Equal(t, uint64(2), subscriptions[1].Indications)
+	assert.Equal(t, 1, subscriptions[1].Streams)
+	assert.Equal(t, request2.Headers, subscriptions[1].Headers)
+	assert.False(t, subscriptions[1].StartTime.IsZero())
+
+	// A resumed subscription keeps its entry
+	assert.Same(t, entry1, registry.add(newSubscribeRequest("sub-1", "trigger-1"), false))
+	assert.Equal(t, 2, registry.list()[0].Streams)
+
+	// Subscriptions whose streams ended without unsubscribing are kept, but are no longer active
+	registry.detach(entry2, false)
+	assert.Len(t, registry.list(), 2)
+	assert.Len(t, registry.active(), 1)
+
+	registry.removeTransaction("test-app", "", "sub-2")
+	registry.remove(entry1)
+	assert.Empty(t, registry.list())
+}
+
+func TestRegistryOrphanCleanup(t *testing.T) {
+	registry, unsubscribes := newTestRegistry(50 * time.Millisecond)
+	request := newSubscribeRequest("sub-1", "trigger-1")
+	entry := registry.add(request, false)
+
+	// Streams which ended for reasons other than the app cancelling them are not orphaned
+	registry.detach(entry, false)
+	registry.add(request, false)
+	registry.detach(entry, true)
+	select {
+	case unsubscribed := <-unsubscribes:
+		assert.Equal(t, request, unsubscribed)
+	case <-time.After(5 * time.Second):
+		t.Fatal("orphaned subscription not unsubscribed")
+	}
+	assert.Empty(t, registry.list())
+}
+
+func TestRegistryOrphanResume(t *testing.T) {
+	registry, unsubscribes := newTestRegistry(100 * time.Millisecond)
+	request := newSubscribeRequest("sub-1", "trigger-1")
+
+	// The subscription is kept if the app resumes it before the orphan timeout
+	entry := registry.add(request, false)
+	registry.detach(entry, true)
+	registry.add(request, false)
+
+	// or unsubscribes it itself
+	entry = registry.add(newSubscribeRequest("sub-2", "trigger-1"), false)
+	registry.detach(entry, true)
+	registry.removeTransaction("test-app", "", "sub-2")
+
+	// and orphans are kept on shutdown
+	entry = registry.add(newSubscribeRequest("sub-3", "trigger-1"), false)
+	registry.detach(entry, true)
+	registry.close()
+
+	select {
+	case unsubscribed := <-unsubscribes:
+		t.Fatalf("unexpected unsubscribe of %+v", unsubscribed)
+	case <-time.After(300 * time.Millisecond):
+	}
+	assert.Len(t, registry.list(), 2)
+}
+
+func TestOrphanedSubscription(t *testing.T) {
+	env := newTestEnvWithOptions(t, Options{MasterPolicy: WaitForMaster, OrphanTimeout: 100 * time.Millisecond}, "e2t-1")
+	env.topo.SetMaster("e2-1", "e2t-1")
+	e2t := env.e2ts["e2t-1"]
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	ctx1, cancel1 := context.WithCancel(ctx)
+	defer cancel1()
+	stream1, _ := env.subscribe(t, ctx1, newSubscribeRequest("sub-1", "trigger-1"))
+	stream2, _ := env.subscribe(t, ctx, newSubscribeRequest("sub-2", "trigger-2"))
+	assert.Equal(t, 2, e2t.Indicate("e2-1", e2api.Indication{Payload: []byte("1")}))
+	assertIndication(t, stream1, "1")
+	assertIndication(t, stream2, "1")
+
+	subscriptions := env.service.Subscriptions()
+	require.Len(t, subscriptions, 2)
+	assert.Equal(t, uint64(1), subscriptions[0].Indications)
+
+	// The app unsubscribes sub-2 explicitly, and abandons sub-1
+	_, err := e2api.NewSubscriptionServiceClient(env.conn).Unsubscribe(ctx, &e2api.UnsubscribeRequest{
+		Headers:       newSubscribeRequest("sub-2", "trigger-2").Headers,
+		TransactionID: "sub-2",
+	})
+	require.NoError(t, err)
+	cancel1()
+	assert.Eventually(t, func() bool {
+		return len(e2t.Unsubscribes()) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	unsubscribes := e2t.Unsubscribes()
+	assert.Equal(t, e2api.TransactionID("sub-2"), unsubscribes[0].TransactionID)
+	assert.Equal(t, e2api.TransactionID("sub-1"), unsubscribes[1].TransactionID)
+	assert.Empty(t, env.service.Subscriptions())
+}
+
+func TestFailedSubscription(t *testing.T) {
+	env := newTestEnvWithOptions(t, Options{MasterPolicy: WaitForMaster, OrphanTimeout: 100 * time.Millisecond}, "e2t-1")
+	env.topo.SetMaster("e2-1", "e2t-1")
+	e2t := env.e2ts["e2t-1"]
+	client := e2api.NewSubscriptionServiceClient(env.conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// A subscription which fails in E2T is no longer registered
+	e2t.Fail(status.Error(codes.InvalidArgument, "invalid subscription"))
+	stream, err := client.Subscribe(ctx, newSubscribeRequest("sub-1", "trigger-1"))
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Eventually(t, func() bool {
+		return len(env.service.Subscriptions()) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// A subscription which E2T could not be reached for is orphaned
+	e2t.Fail(status.Error(codes.Unavailable, "unavailable"))
+	stream, err = client.Subscribe(ctx, newSubscribeRequest("sub-2", "trigger-1"))
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	e2t.Fail(nil)
+	assert.Eventually(t, func() bool {
+		unsubscribes := e2t.Unsubscribes()
+		return len(unsubscribes) == 1 && unsubscribes[0].TransactionID == "sub-2"
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Empty(t, env.service.Subscriptions())
+}
+
+func TestRegistryRestore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	registry, _ := newTestRegistry(0)
+	require.NoError(t, registry.restore(newStateStore(path)))
+	registry.add(newSubscribeRequest("sub-1", "trigger-1"), false)
+	entry2 := registry.add(newSubscribeRequest("sub-2", "trigger-2"), false)
+	registry.detach(entry2, false)
+	shared := newSubscribeRequest("onos-proxy-0123456789abcdef", "trigger-3")
+	registry.add(shared, true)
+	registry.add(newSubscribeRequest("sub-4", "trigger-4"), false)
+	registry.removeTransaction("test-app", "", "sub-4")
+	subscriptions := registry.list()
+
+	// The subscriptions are restored with their start time, without app streams
+	store := newStateStore(path)
+	restored, _ := newTestRegistry(0)
+	require.NoError(t, restored.restore(store))
+	restoredSubscriptions := restored.list()
+	require.Len(t, restoredSubscriptions, 3)
+	for i, subscription := range restoredSubscriptions {
+		assert.Equal(t, subscriptions[i].Headers, subscription.Headers)
+		assert.Equal(t, subscriptions[i].TransactionID, subscription.TransactionID)
+		assert.True(t, subscriptions[i].StartTime.Equal(subscription.StartTime))
+		assert.Equal(t, subscriptions[i].Shared, subscription.Shared)
+		assert.Equal(t, SubscriptionRestored, subscription.Status)
+		assert.Zero(t, subscription.Streams)
+	}
+
+	// The app resumes sub-1, sub-2 is no longer in E2T and the shared subscription is deleted
+	restored.add(newSubscribeRequest("sub-1", "trigger-1"), false)
+	deleted := restored.reconcile([]e2api.Channel{
+		{ChannelMeta: e2api.ChannelMeta{AppID: "test-app", TransactionID: "sub-1"}},
+		{ChannelMeta: e2api.ChannelMeta{AppID: "test-app", TransactionID: shared.TransactionID}},
+	})
+	require.Len(t, deleted, 1)
+	assert.Equal(t, shared.TransactionID, deleted[0].TransactionID)
+	restoredSubscriptions = restored.list()
+	require.Len(t, restoredSubscriptions, 1)
+	assert.Equal(t, SubscriptionActive, restoredSubscriptions[0].Status)
+	assert.True(t, subscriptions[1].StartTime.Equal(restoredSubscriptions[0].StartTime))
+
+	state, err := store.load()
+	require.NoError(t, err)
+	require.Len(t, state.Subscriptions, 1)
+	assert.Equal(t, e2api.TransactionID("sub-1"), state.Subscriptions[0].TransactionID)
+	assert.Equal(t, SubscriptionActive, state.Subscriptions[0].Status)
+}
+
+func TestRegistryAppInstances(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	registry, _ := newTestRegistry(0)
+	require.NoError(t, registry.restore(newStateStore(path)))
+	request1 := newSubscribeRequest("sub-1", "trigger-1")
+	request1.Headers.AppInstanceID = "test-app-1"
+	request2 := newSubscribeRequest("sub-1", "trigger-1")
+	request2.Headers.AppInstanceID = "test-app-2"
+
+	// The same transaction of different instances of an app are different subscriptions
+	entry1 := registry.add(request1, false)
+	assert.NotSame(t, entry1, registry.add(request2, false))
+	subscriptions := registry.list()
+	require.Len(t, subscriptions, 2)
+	assert.Equal(t, e2api.AppInstanceID("test-app-1"), subscriptions[0].Headers.AppInstanceID)
+	assert.Equal(t, e2api.AppInstanceID("test-app-2"), subscriptions[1].Headers.AppInstanceID)
+
+	// which are persisted and reconciled separately
+	restored, _ := newTestRegistry(0)
+	require.NoError(t, restored.restore(newStateStore(path)))
+	require.Len(t, restored.list(), 2)
+	restored.reconcile([]e2api.Channel{
+		{ChannelMeta: e2api.ChannelMeta{AppID: "test-app", AppInstanceID: "test-app-2", TransactionID: "sub-1"}},
+	})
+	subscriptions = restored.list()
+	require.Len(t, subscriptions, 1)
+	assert.Equal(t, e2api.AppInstanceID("test-app-2"), subscriptions[0].Headers.AppInstanceID)
+
+	registry.removeTransaction("test-app", "test-app-1", "sub-1")
+	subscriptions = registry.list()
+	require.Len(t, subscriptions, 1)
+	assert.Equal(t, e2api.AppInstanceID("test-app-2"), subscriptions[0].Headers.AppInstanceID)
+}
+
+func TestSubscriptionRecovery(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	env := newTestEnvWithOptions(t, Options{MasterPolicy: WaitForMaster, StatePath: statePath}, "e2t-1")
+	env.topo.SetMaster("e2-1", "e2t-1")
+	e2t := env.e2ts["e2t-1"]
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	streamCtx, cancelStreams := context.WithCancel(ctx)
+	env.subscribe(t, streamCtx, newSubscribeRequest("sub-1", "trigger-1"))
+	env.subscribe(t, streamCtx, newSubscribeRequest("sub-2", "trigger-2"))
+	env.subscribe(t, streamCtx, newSubscribeRequest("sub-3", "trigger-3"))
+	started := env.service.Subscriptions()[0].StartTime
+
+	// The proxy container restarts, breaking the app streams, and sub-2 is deleted from E2T meanwhile
+	env.service.Close()
+	cancelStreams()
+	assert.Eventually(t, func() bool {
+		return e2t.Streams() == 0 && detached(statePath)
+	}, 5*time.Second, 10*time.Millisecond)
+	e2tConn, err := grpc.Dial(e2t.Address(), env.network.DialOptions()...)
+	require.NoError(t, err)
+	defer e2tConn.Close()
+	_, err = e2api.NewSubscriptionServiceClient(e2tConn).Unsubscribe(ctx, &e2api.UnsubscribeRequest{
+		Headers:       newSubscribeRequest("sub-2", "trigger-2").Headers,
+		TransactionID: "sub-2",
+	})
+	require.NoError(t, err)
+
+	service := NewProxyService(env.e2tConn, env.builder, Options{
+		MasterPolicy:  WaitForMaster,
+		StatePath:     statePath,
+		OrphanTimeout: 200 * time.Millisecond,
+	})
+	defer service.Close()
+	const restartedProxyAddress = "onos-proxy-restarted:5151"
+	env.network.Serve(restartedProxyAddress, service.Register)
+	conn, err := grpc.Dial(restartedProxyAddress, env.network.DialOptions()...)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// The app resumes sub-1, sub-2 is forgotten and the abandoned sub-3 is deleted once orphaned
+	stream, err := e2api.NewSubscriptionServiceClient(conn).Subscribe(ctx, newSubscribeRequest("sub-1", "trigger-1"))
+	require.NoError(t, err)
+	response, err := stream.Recv()
+	require.NoError(t, err)
+	assert.NotNil(t, response.GetAck())
+	assert.Eventually(t, func() bool {
+		return len(service.Subscriptions()) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	subscriptions := service.Subscriptions()
+	assert.Equal(t, e2api.TransactionID("sub-1"), subscriptions[0].TransactionID)
+	assert.Equal(t, SubscriptionActive, subscriptions[0].Status)
+	assert.True(t, started.Equal(subscriptions[0].StartTime))
+	assert.Eventually(t, func() bool {
+		return len(e2t.Unsubscribes()) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	unsubscribes := e2t.Unsubscribes()
+	require.Len(t, unsubscribes, 2)
+	assert.Equal(t, e2api.TransactionID("sub-3"), unsubscribes[1].TransactionID)
+
+	// The restarted proxy is done saving its state once the app's stream ends
+	service.Close()
+	cancel()
+	assert.Eventually(t, func() bool {
+		return detached(statePath)
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+// detached returns whether none of the subscriptions in the state persisted at the given path has app
+// streams, and so whether the proxy is done saving the state of the streams it closed
+func detached(path string) bool {
+	state, err := newStateStore(path).load()
+	if err != nil {
+		return false
+	}
+	for _, subscription := range state.Subscriptions {
+		if subscription.Status == SubscriptionActive {
+			return false
+		}
+	}
+	return true
+}
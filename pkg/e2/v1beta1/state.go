@@ -0,0 +1,113 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package v1beta1
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	e2api "github.com/onosproject/onos-api/go/onos/e2t/e2/v1beta1"
+)
+
+// SubscriptionStatus is the last known status of a subscription in the registry
+type SubscriptionStatus string
+
+const (
+	// SubscriptionActive indicates the subscription is being received by app streams
+	SubscriptionActive SubscriptionStatus = "active"
+	// SubscriptionDetached indicates the app streams of the subscription ended without unsubscribing
+	SubscriptionDetached SubscriptionStatus = "detached"
+	// SubscriptionRestored indicates the subscription was restored from the persisted state of a previous run
+	// of the proxy and has not yet been reconciled with E2T nor resumed by the app
+	SubscriptionRestored SubscriptionStatus = "restored"
+)
+
+// subscriptionState is the state of the subscription registry persisted across restarts of the proxy
+type subscriptionState struct {
+	Subscriptions []persistedSubscription `json:"subscriptions"`
+}
+
+// persistedSubscription is a subscription of the registry as persisted
+type persistedSubscription struct {
+	AppID         e2api.AppID         `json:"appId"`
+	AppInstanceID e2api.AppInstanceID `json:"appInstanceId,omitempty"`
+	TransactionID e2api.TransactionID `json:"transactionId"`
+	E2NodeID      e2api.E2NodeID      `json:"e2NodeId"`
+	Shared        bool                `json:"shared,omitempty"`
+	StartTime     time.Time           `json:"startTime"`
+	Status        SubscriptionStatus  `json:"status"`
+	// Request is the protobuf encoding of the subscribe request
+	Request []byte `json:"request"`
+}
+
+func newStateStore(path string) *stateStore {
+	return &stateStore{path: path}
+}
+
+// stateStore persists the state of the subscription registry to a local file, e.g. on a pod volume which
+// outlives the proxy container
+type stateStore struct {
+	path string
+	// version is the version of the last state saved; older states saved concurrently are skipped
+	version uint64
+	mu      sync.Mutex
+}
+
+// load reads the persisted state; a missing file is an empty state
+func (s *stateStore) load() (*subscriptionState, error) {
+	bytes, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return &subscriptionState{}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to read subscription state: %v", err)
+	}
+	state := &subscriptionState{}
+	if err := json.Unmarshal(bytes, state); err != nil {
+		return nil, fmt.Errorf("unable to parse subscription state %s: %v", s.path, err)
+	}
+	return state, nil
+}
+
+// save writes the given version of the state unless a later one has been saved already. The file is replaced
+// atomically so that a crash while saving leaves the previous state intact.
+func (s *stateStore) save(version uint64, state *subscriptionState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if version <= s.version {
+		return
+	}
+	s.version = version
+	if err := s.write(state); err != nil {
+		log.Warnf("Unable to persist subscription state to %s: %v", s.path, err)
+	}
+}
+
+func (s *stateStore) write(state *subscriptionState) error {
+	bytes, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.Write(bytes); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), s.path)
+}
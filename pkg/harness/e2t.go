@@ -0,0 +1,376 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package harness
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	e2api "github.com/onosproject/onos-api/go/onos/e2t/e2/v1beta1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const indicationBufferSize = 100
+
+// NewE2TServer creates a new fake E2T server identifying itself by the given address
+func NewE2TServer(address string) *E2TServer {
+	return &E2TServer{
+		address:       address,
+		streams:       make(map[*subscribeStream]bool),
+		channels:      make(map[e2api.ChannelID]e2api.Channel),
+		subscriptions: make(map[e2api.SubscriptionID]e2api.Subscription),
+		watchers:      make(map[chan e2api.ChannelEvent]bool),
+	}
+}
+
+// E2TServer is a fake of the E2T control and subscription services which records the requests it serves.
+// Control responses carry the server's address as the outcome payload and subscription acknowledgements
+// carry it in the channel ID, allowing tests to tell which instance served a request. The channels of the
+// subscriptions are kept until they are unsubscribed, and can be listed and watched via the subscription admin
+// service along with the channels and subscriptions set by tests.
+type E2TServer struct {
+	address       string
+	controls      []e2api.ControlRequest
+	subscribes    []e2api.SubscribeRequest
+	unsubscribes  []e2api.UnsubscribeRequest
+	streams       map[*subscribeStream]bool
+	channels      map[e2api.ChannelID]e2api.Channel
+	subscriptions map[e2api.SubscriptionID]e2api.Subscription
+	watchers      map[chan e2api.ChannelEvent]bool
+	err           error
+	delay         time.Duration
+	mu            sync.Mutex
+}
+
+type subscribeStream struct {
+	request     e2api.SubscribeRequest
+	indications chan e2api.Indication
+}
+
+// Address returns the address of the server
+func (s *E2TServer) Address() string {
+	return s.address
+}
+
+// Register registers the fake E2T services with the given gRPC server
+func (s *E2TServer) Register(server *grpc.Server) {
+	e2api.RegisterControlServiceServer(server, &e2tControlServer{E2TServer: s})
+	e2api.RegisterSubscriptionServiceServer(server, &e2tSubscriptionServer{E2TServer: s})
+	e2api.RegisterSubscriptionAdminServiceServer(server, &e2tSubscriptionAdminServer{E2TServer: s})
+}
+
+// Fail causes all subsequent requests to fail with the given error; a nil error restores normal operation
+func (s *E2TServer) Fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+// DelayUnsubscribes delays the subsequent unsubscribe requests by the given duration before serving them, unless
+// they are cancelled first
+func (s *E2TServer) DelayUnsubscribes(delay time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.delay = delay
+}
+
+// SetChannel adds the given channel, or replaces the channel with the same ID, as if changed by another client
+func (s *E2TServer) SetChannel(channel e2api.Channel) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	eventType := e2api.ChannelEventType_CHANNEL_CREATED
+	if _, ok := s.channels[channel.ID]; ok {
+		eventType = e2api.ChannelEventType_CHANNEL_UPDATED
+	}
+	s.channels[channel.ID] = channel
+	s.notifyLocked(eventType, channel)
+}
+
+// SetSubscription adds the given subscription, or replaces the subscription with the same ID
+func (s *E2TServer) SetSubscription(subscription e2api.Subscription) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subscriptions[subscription.ID] = subscription
+}
+
+// Controls returns the control requests served so far
+func (s *E2TServer) Controls() []e2api.ControlRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]e2api.ControlRequest(nil), s.controls...)
+}
+
+// Subscribes returns the subscribe requests served so far
+func (s *E2TServer) Subscribes() []e2api.SubscribeRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]e2api.SubscribeRequest(nil), s.subscribes...)
+}
+
+// Unsubscribes returns the unsubscribe requests served so far
+func (s *E2TServer) Unsubscribes() []e2api.UnsubscribeRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]e2api.UnsubscribeRequest(nil), s.unsubscribes...)
+}
+
+// Channels returns the channels of the subscriptions which have not been unsubscribed, ordered by ID
+func (s *E2TServer) Channels() []e2api.Channel {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	channels := make([]e2api.Channel, 0, len(s.channels))
+	for _, channel := range s.channels {
+		channels = append(channels, channel)
+	}
+	sort.Slice(channels, func(i, j int) bool {
+		return channels[i].ID < channels[j].ID
+	})
+	return channels
+}
+
+// Streams returns the number of open subscription streams
+func (s *E2TServer) Streams() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.streams)
+}
+
+// Indicate sends the given indication on all open subscription streams for the given E2 node, returning
+// the number of streams it was sent on
+func (s *E2TServer) Indicate(nodeID e2api.E2NodeID, indication e2api.Indication) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var count int
+	for stream := range s.streams {
+		if stream.request.Headers.E2NodeID == nodeID {
+			stream.indications <- indication
+			count++
+		}
+	}
+	return count
+}
+
+// ChannelID returns the channel ID acknowledged by the server for the given subscribe request
+func (s *E2TServer) ChannelID(request *e2api.SubscribeRequest) e2api.ChannelID {
+	return e2api.ChannelID(fmt.Sprintf("%s/%s", s.address, request.TransactionID))
+}
+
+// notifyLocked sends an event for the given channel to the channel watchers; the server must be locked
+func (s *E2TServer) notifyLocked(eventType e2api.ChannelEventType, channel e2api.Channel) {
+	for watcher := range s.watchers {
+		watcher <- e2api.ChannelEvent{Type: eventType, Channel: channel}
+	}
+}
+
+type e2tControlServer struct {
+	e2api.UnimplementedControlServiceServer
+	*E2TServer
+}
+
+func (s *e2tControlServer) Control(ctx context.Context, request *e2api.ControlRequest) (*e2api.ControlResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return nil, status.Convert(s.err).Err()
+	}
+	s.controls = append(s.controls, *request)
+	return &e2api.ControlResponse{
+		Headers: e2api.ResponseHeaders{Encoding: request.Headers.Encoding},
+		Outcome: e2api.ControlOutcome{Payload: []byte(s.address)},
+	}, nil
+}
+
+type e2tSubscriptionServer struct {
+	e2api.UnimplementedSubscriptionServiceServer
+	*E2TServer
+}
+
+func (s *e2tSubscriptionServer) Subscribe(request *e2api.SubscribeRequest, server e2api.SubscriptionService_SubscribeServer) error {
+	stream := &subscribeStream{
+		request:     *request,
+		indications: make(chan e2api.Indication, indicationBufferSize),
+	}
+	s.mu.Lock()
+	if s.err != nil {
+		s.mu.Unlock()
+		return status.Convert(s.err).Err()
+	}
+	s.subscribes = append(s.subscribes, *request)
+	s.streams[stream] = true
+	channelID := s.ChannelID(request)
+	if _, ok := s.channels[channelID]; !ok {
+		s.channels[channelID] = e2api.Channel{
+			ID: channelID,
+			ChannelMeta: e2api.ChannelMeta{
+				AppID:         request.Headers.AppID,
+				AppInstanceID: request.Headers.AppInstanceID,
+				E2NodeID:      request.Headers.E2NodeID,
+				TransactionID: request.TransactionID,
+				ServiceModel:  request.Headers.ServiceModel,
+				Encoding:      request.Headers.Encoding,
+				Revision:      1,
+			},
+		}
+		s.notifyLocked(e2api.ChannelEventType_CHANNEL_CREATED, s.channels[channelID])
+	}
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.streams, stream)
+		s.mu.Unlock()
+	}()
+
+	ack := &e2api.SubscribeResponse{
+		Headers: e2api.ResponseHeaders{Encoding: request.Headers.Encoding},
+		Message: &e2api.SubscribeResponse_Ack{Ack: &e2api.Acknowledgement{ChannelID: s.ChannelID(request)}},
+	}
+	if err := server.Send(ack); err != nil {
+		return err
+	}
+	for {
+		select {
+		case indication := <-stream.indications:
+			response := &e2api.SubscribeResponse{
+				Headers: e2api.ResponseHeaders{Encoding: request.Headers.Encoding},
+				Message: &e2api.SubscribeResponse_Indication{Indication: &indication},
+			}
+			if err := server.Send(response); err != nil {
+				return err
+			}
+		case <-server.Context().Done():
+			return status.FromContextError(server.Context().Err()).Err()
+		}
+	}
+}
+
+func (s *e2tSubscriptionServer) Unsubscribe(ctx context.Context, request *e2api.UnsubscribeRequest) (*e2api.UnsubscribeResponse, error) {
+	s.mu.Lock()
+	delay := s.delay
+	s.mu.Unlock()
+	select {
+	case <-time.After(delay):
+	case <-ctx.Done():
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return nil, status.Convert(s.err).Err()
+	}
+	s.unsubscribes = append(s.unsubscribes, *request)
+	for id, channel := range s.channels {
+		if channel.AppID == request.Headers.AppID && channel.AppInstanceID == request.Headers.AppInstanceID &&
+			channel.TransactionID == request.TransactionID && channel.E2NodeID == request.Headers.E2NodeID {
+			delete(s.channels, id)
+			s.notifyLocked(e2api.ChannelEventType_CHANNEL_DELETED, channel)
+		}
+	}
+	return &e2api.UnsubscribeResponse{
+		Headers: e2api.ResponseHeaders{Encoding: request.Headers.Encoding},
+	}, nil
+}
+
+type e2tSubscriptionAdminServer struct {
+	e2api.UnimplementedSubscriptionAdminServiceServer
+	*E2TServer
+}
+
+func (s *e2tSubscriptionAdminServer) ListChannels(ctx context.Context, request *e2api.ListChannelsRequest) (*e2api.ListChannelsResponse, error) {
+	s.mu.Lock()
+	err := s.err
+	s.mu.Unlock()
+	if err != nil {
+		return nil, status.Convert(err).Err()
+	}
+	return &e2api.ListChannelsResponse{Channels: s.Channels()}, nil
+}
+
+func (s *e2tSubscriptionAdminServer) GetChannel(ctx context.Context, request *e2api.GetChannelRequest) (*e2api.GetChannelResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return nil, status.Convert(s.err).Err()
+	}
+	channel, ok := s.channels[request.ChannelID]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "channel %s not found", request.ChannelID)
+	}
+	return &e2api.GetChannelResponse{Channel: channel}, nil
+}
+
+func (s *e2tSubscriptionAdminServer) GetSubscription(ctx context.Context, request *e2api.GetSubscriptionRequest) (*e2api.GetSubscriptionResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return nil, status.Convert(s.err).Err()
+	}
+	subscription, ok := s.subscriptions[request.SubscriptionID]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "subscription %s not found", request.SubscriptionID)
+	}
+	return &e2api.GetSubscriptionResponse{Subscription: subscription}, nil
+}
+
+func (s *e2tSubscriptionAdminServer) ListSubscriptions(ctx context.Context, request *e2api.ListSubscriptionsRequest) (*e2api.ListSubscriptionsResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return nil, status.Convert(s.err).Err()
+	}
+	subscriptions := make([]e2api.Subscription, 0, len(s.subscriptions))
+	for _, subscription := range s.subscriptions {
+		subscriptions = append(subscriptions, subscription)
+	}
+	sort.Slice(subscriptions, func(i, j int) bool {
+		return subscriptions[i].ID < subscriptions[j].ID
+	})
+	return &e2api.ListSubscriptionsResponse{Subscriptions: subscriptions}, nil
+}
+
+func (s *e2tSubscriptionAdminServer) WatchChannels(request *e2api.WatchChannelsRequest, server e2api.SubscriptionAdminService_WatchChannelsServer) error {
+	watcher := make(chan e2api.ChannelEvent, indicationBufferSize)
+	s.mu.Lock()
+	if s.err != nil {
+		s.mu.Unlock()
+		return status.Convert(s.err).Err()
+	}
+	var events []e2api.ChannelEvent
+	if !request.NoReplay {
+		for _, channel := range s.channels {
+			events = append(events, e2api.ChannelEvent{Type: e2api.ChannelEventType_CHANNEL_REPLAYED, Channel: channel})
+		}
+	}
+	s.watchers[watcher] = true
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.watchers, watcher)
+		s.mu.Unlock()
+	}()
+
+	for _, event := range events {
+		if err := server.Send(&e2api.WatchChannelsResponse{Event: event}); err != nil {
+			return err
+		}
+	}
+	for {
+		select {
+		case event := <-watcher:
+			if err := server.Send(&e2api.WatchChannelsResponse{Event: event}); err != nil {
+				return err
+			}
+		case <-server.Context().Done():
+			return status.FromContextError(server.Context().Err()).Err()
+		}
+	}
+}
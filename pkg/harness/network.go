@@ -0,0 +1,88 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package harness provides in-process fakes of the topo and E2T services for testing the proxy end-to-end
+// without a cluster. Servers are bound to named addresses on an in-memory Network; connections dialed
+// through the network's dialer are routed to the listener bound to the dialed address.
+package harness
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const bufferSize = 1024 * 1024
+
+// NewNetwork creates a new in-memory network
+func NewNetwork() *Network {
+	return &Network{
+		listeners: make(map[string]*bufconn.Listener),
+	}
+}
+
+// Network is an in-memory network of gRPC servers addressed by name
+type Network struct {
+	listeners map[string]*bufconn.Listener
+	servers   []*grpc.Server
+	mu        sync.Mutex
+}
+
+// Listen binds a new listener to the given address, replacing any listener previously bound to it
+func (n *Network) Listen(address string) net.Listener {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	lis := bufconn.Listen(bufferSize)
+	n.listeners[address] = lis
+	return lis
+}
+
+// Serve starts a gRPC server on the given address with the given registration function
+func (n *Network) Serve(address string, register func(*grpc.Server), opts ...grpc.ServerOption) *grpc.Server {
+	lis := n.Listen(address)
+	server := grpc.NewServer(opts...)
+	register(server)
+	go func() {
+		_ = server.Serve(lis)
+	}()
+	n.mu.Lock()
+	n.servers = append(n.servers, server)
+	n.mu.Unlock()
+	return server
+}
+
+// Dial connects to the listener bound to the given address
+func (n *Network) Dial(ctx context.Context, address string) (net.Conn, error) {
+	n.mu.Lock()
+	lis, ok := n.listeners[address]
+	n.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("no listener bound to %s", address)
+	}
+	return lis.DialContext(ctx)
+}
+
+// DialOptions returns the gRPC dial options for connecting via the network without transport security
+func (n *Network) DialOptions() []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithContextDialer(n.Dial),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	}
+}
+
+// Close stops all servers started on the network
+func (n *Network) Close() {
+	n.mu.Lock()
+	servers := n.servers
+	n.servers = nil
+	n.mu.Unlock()
+	for _, server := range servers {
+		server.Stop()
+	}
+}
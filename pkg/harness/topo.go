@@ -0,0 +1,238 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package harness
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/onosproject/onos-api/go/onos/topo"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const watchBufferSize = 1000
+
+// NewTopoServer creates a new fake topo server with no objects
+func NewTopoServer() *TopoServer {
+	return &TopoServer{
+		objects:  make(map[topo.ID]topo.Object),
+		watchers: make(map[*topoWatcher]bool),
+	}
+}
+
+// TopoServer is a scriptable fake of the topo service. Objects are added, updated and removed by the
+// test and the corresponding events are streamed to all open watches. Request filters are ignored.
+type TopoServer struct {
+	topo.UnimplementedTopoServer
+	objects  map[topo.ID]topo.Object
+	watchers map[*topoWatcher]bool
+	revision topo.Revision
+	mu       sync.Mutex
+}
+
+type topoWatcher struct {
+	ch     chan topo.Event
+	broken chan struct{}
+}
+
+// Register registers the fake topo service with the given gRPC server
+func (s *TopoServer) Register(server *grpc.Server) {
+	topo.RegisterTopoServer(server, s)
+}
+
+// Set adds or updates the given objects, emitting an ADDED or UPDATED event for each
+func (s *TopoServer) Set(objects ...topo.Object) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, object := range objects {
+		eventType := topo.EventType_ADDED
+		if _, ok := s.objects[object.ID]; ok {
+			eventType = topo.EventType_UPDATED
+		}
+		s.revision++
+		object.Revision = s.revision
+		s.objects[object.ID] = object
+		s.emit(topo.Event{Type: eventType, Object: object})
+	}
+}
+
+// Remove removes the objects with the given IDs, emitting a REMOVED event for each
+func (s *TopoServer) Remove(ids ...topo.ID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range ids {
+		if object, ok := s.objects[id]; ok {
+			delete(s.objects, id)
+			s.emit(topo.Event{Type: topo.EventType_REMOVED, Object: object})
+		}
+	}
+}
+
+// Object returns the object with the given ID
+func (s *TopoServer) Object(id topo.ID) (topo.Object, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	object, ok := s.objects[id]
+	return object, ok
+}
+
+// AddE2T adds an E2T instance serving the E2T interface at the given IP and port
+func (s *TopoServer) AddE2T(id topo.ID, ip string, port uint32) {
+	s.Set(NewE2T(id, ip, port))
+}
+
+// AddE2Node adds an E2 node with no master
+func (s *TopoServer) AddE2Node(id topo.ID) {
+	s.Set(NewE2Node(id, 0, ""))
+}
+
+// SetMaster makes the given E2T instance the master of the given E2 node, adding the controls relation
+// between them if needed and incrementing the node's mastership term
+func (s *TopoServer) SetMaster(nodeID topo.ID, e2tID topo.ID) {
+	relationID := ControlsID(e2tID, nodeID)
+	if _, ok := s.Object(relationID); !ok {
+		s.Set(NewControls(e2tID, nodeID))
+	}
+	var term uint64
+	if node, ok := s.Object(nodeID); ok {
+		var mastership topo.MastershipState
+		_ = node.GetAspect(&mastership)
+		term = mastership.Term
+	}
+	s.Set(NewE2Node(nodeID, term+1, relationID))
+}
+
+// BreakWatches fails all open watches with an Unavailable error, as if the topo service restarted
+func (s *TopoServer) BreakWatches() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for watcher := range s.watchers {
+		close(watcher.broken)
+		delete(s.watchers, watcher)
+	}
+}
+
+// Watches returns the number of open watches
+func (s *TopoServer) Watches() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.watchers)
+}
+
+// emit sends the given event to all watchers; it must be called with the lock held
+func (s *TopoServer) emit(event topo.Event) {
+	for watcher := range s.watchers {
+		watcher.ch <- event
+	}
+}
+
+// list returns the present objects ordered by ID; it must be called with the lock held
+func (s *TopoServer) list() []topo.Object {
+	objects := make([]topo.Object, 0, len(s.objects))
+	for _, object := range s.objects {
+		objects = append(objects, object)
+	}
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].ID < objects[j].ID
+	})
+	return objects
+}
+
+func (s *TopoServer) Get(ctx context.Context, request *topo.GetRequest) (*topo.GetResponse, error) {
+	object, ok := s.Object(request.ID)
+	if !ok {
+		return nil, errors.Status(errors.NewNotFound("object %s not found", request.ID)).Err()
+	}
+	return &topo.GetResponse{Object: &object}, nil
+}
+
+func (s *TopoServer) List(ctx context.Context, request *topo.ListRequest) (*topo.ListResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return &topo.ListResponse{Objects: s.list()}, nil
+}
+
+func (s *TopoServer) Watch(request *topo.WatchRequest, server topo.Topo_WatchServer) error {
+	watcher := &topoWatcher{
+		ch:     make(chan topo.Event, watchBufferSize),
+		broken: make(chan struct{}),
+	}
+	s.mu.Lock()
+	if !request.Noreplay {
+		for _, object := range s.list() {
+			watcher.ch <- topo.Event{Type: topo.EventType_NONE, Object: object}
+		}
+	}
+	s.watchers[watcher] = true
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.watchers, watcher)
+		s.mu.Unlock()
+	}()
+
+	for {
+		select {
+		case event := <-watcher.ch:
+			if err := server.Send(&topo.WatchResponse{Event: event}); err != nil {
+				return err
+			}
+		case <-watcher.broken:
+			return status.Error(codes.Unavailable, "watch broken")
+		case <-server.Context().Done():
+			return status.FromContextError(server.Context().Err()).Err()
+		}
+	}
+}
+
+// ControlsID returns the ID of the controls relation between the given E2T instance and E2 node
+func ControlsID(e2tID topo.ID, nodeID topo.ID) topo.ID {
+	return topo.ID(fmt.Sprintf("%s-%s", e2tID, nodeID))
+}
+
+// NewE2T returns an E2T entity serving the E2T interface at the given IP and port
+func NewE2T(id topo.ID, ip string, port uint32) topo.Object {
+	object := topo.Object{
+		ID:   id,
+		Type: topo.Object_ENTITY,
+		Obj:  &topo.Object_Entity{Entity: &topo.Entity{KindID: topo.E2T}},
+	}
+	_ = object.SetAspect(&topo.E2TInfo{
+		Interfaces: []*topo.Interface{{Type: topo.Interface_INTERFACE_E2T, IP: ip, Port: port}},
+	})
+	return object
+}
+
+// NewE2Node returns an E2 node entity mastered via the given controls relation in the given term
+func NewE2Node(id topo.ID, term uint64, controlsID topo.ID) topo.Object {
+	object := topo.Object{
+		ID:   id,
+		Type: topo.Object_ENTITY,
+		Obj:  &topo.Object_Entity{Entity: &topo.Entity{KindID: topo.E2NODE}},
+	}
+	_ = object.SetAspect(&topo.MastershipState{Term: term, NodeId: string(controlsID)})
+	return object
+}
+
+// NewControls returns the controls relation between the given E2T instance and E2 node
+func NewControls(e2tID topo.ID, nodeID topo.ID) topo.Object {
+	return topo.Object{
+		ID:   ControlsID(e2tID, nodeID),
+		Type: topo.Object_RELATION,
+		Obj: &topo.Object_Relation{Relation: &topo.Relation{
+			KindID:      topo.CONTROLS,
+			SrcEntityID: e2tID,
+			TgtEntityID: nodeID,
+		}},
+	}
+}
+
+var _ topo.TopoServer = (*TopoServer)(nil)
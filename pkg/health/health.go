@@ -0,0 +1,181 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/onosproject/onos-lib-go/pkg/northbound"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// ReadinessService is the gRPC health service name reporting readiness; it is also reported as the overall status
+	ReadinessService = "readiness"
+	// LivenessService is the gRPC health service name reporting liveness
+	LivenessService = "liveness"
+
+	// watchInterval is the interval at which the status is re-evaluated for gRPC health watches
+	watchInterval = time.Second
+)
+
+// Checker reports the liveness and readiness of a component
+type Checker interface {
+	// Live returns an error if the component has failed and the process should be restarted
+	Live() error
+	// Ready returns an error if the component is not yet able to serve requests
+	Ready() error
+}
+
+// NewMonitor creates a new monitor aggregating the health of the given components
+func NewMonitor(checkers ...Checker) *Monitor {
+	return &Monitor{
+		checkers:   checkers,
+		shutdownCh: make(chan struct{}),
+	}
+}
+
+// Monitor aggregates the health of the proxy components and reports it via gRPC and HTTP
+type Monitor struct {
+	checkers   []Checker
+	shutdownCh chan struct{}
+	shutdown   sync.Once
+}
+
+// Shutdown reports the proxy as no longer ready so that no new requests are routed to it, and ends the
+// gRPC health watches so that they do not hold up the shutdown of the server
+func (m *Monitor) Shutdown() {
+	m.shutdown.Do(func() {
+		close(m.shutdownCh)
+	})
+}
+
+// Live returns an error if any of the components is not live
+func (m *Monitor) Live() error {
+	for _, checker := range m.checkers {
+		if err := checker.Live(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Ready returns an error if any of the components is not ready
+func (m *Monitor) Ready() error {
+	select {
+	case <-m.shutdownCh:
+		return fmt.Errorf("shutting down")
+	default:
+	}
+	for _, checker := range m.checkers {
+		if err := checker.Ready(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// check evaluates the status of the given gRPC health service name
+func (m *Monitor) check(service string) (healthpb.HealthCheckResponse_ServingStatus, error) {
+	var err error
+	switch service {
+	case "", ReadinessService:
+		err = m.Ready()
+	case LivenessService:
+		err = m.Live()
+	default:
+		return healthpb.HealthCheckResponse_SERVICE_UNKNOWN, status.Errorf(codes.NotFound, "unknown service %q", service)
+	}
+	if err != nil {
+		return healthpb.HealthCheckResponse_NOT_SERVING, nil
+	}
+	return healthpb.HealthCheckResponse_SERVING, nil
+}
+
+// RegisterRoutes registers the /healthz and /readyz HTTP endpoints with the given mux
+func (m *Monitor) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/healthz", handler(m.Live))
+	mux.HandleFunc("/readyz", handler(m.Ready))
+}
+
+func handler(check func() error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if err := check(); err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = fmt.Fprintln(w, err.Error())
+			return
+		}
+		_, _ = fmt.Fprintln(w, "ok")
+	}
+}
+
+// NewService creates a northbound service exposing the monitor via the grpc.health.v1 Health service
+func NewService(monitor *Monitor) northbound.Service {
+	return &Service{
+		monitor: monitor,
+	}
+}
+
+// Service is a Service implementation for the gRPC health service
+type Service struct {
+	northbound.Service
+	monitor *Monitor
+}
+
+// Register registers the Service with the gRPC server.
+func (s Service) Register(r *grpc.Server) {
+	healthpb.RegisterHealthServer(r, &Server{monitor: s.monitor})
+}
+
+// Server implements the grpc.health.v1 Health service
+type Server struct {
+	monitor *Monitor
+}
+
+func (s *Server) Check(ctx context.Context, request *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	servingStatus, err := s.monitor.check(request.Service)
+	if err != nil {
+		return nil, err
+	}
+	return &healthpb.HealthCheckResponse{Status: servingStatus}, nil
+}
+
+func (s *Server) Watch(request *healthpb.HealthCheckRequest, server healthpb.Health_WatchServer) error {
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+	lastStatus := healthpb.HealthCheckResponse_UNKNOWN
+	shutdown := false
+	for {
+		// Unknown services are reported as such rather than failing the watch, per the health protocol
+		servingStatus, _ := s.monitor.check(request.Service)
+		if servingStatus != lastStatus {
+			if err := server.Send(&healthpb.HealthCheckResponse{Status: servingStatus}); err != nil {
+				return err
+			}
+			lastStatus = servingStatus
+		}
+		if shutdown {
+			return status.Error(codes.Unavailable, "shutting down")
+		}
+		select {
+		case <-ticker.C:
+		case <-s.monitor.shutdownCh:
+			// The status is sent once more so that watchers learn the proxy is no longer ready
+			shutdown = true
+		case <-server.Context().Done():
+			return status.FromContextError(server.Context().Err()).Err()
+		}
+	}
+}
+
+var _ healthpb.HealthServer = (*Server)(nil)
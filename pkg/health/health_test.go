@@ -0,0 +1,115 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+type testChecker struct {
+	live  error
+	ready error
+}
+
+func (c *testChecker) Live() error {
+	return c.live
+}
+
+func (c *testChecker) Ready() error {
+	return c.ready
+}
+
+func get(t *testing.T, handler http.Handler, path string) int {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+	return recorder.Code
+}
+
+func TestHTTP(t *testing.T) {
+	checker := &testChecker{ready: fmt.Errorf("not ready")}
+	mux := http.NewServeMux()
+	NewMonitor(checker).RegisterRoutes(mux)
+
+	assert.Equal(t, http.StatusOK, get(t, mux, "/healthz"))
+	assert.Equal(t, http.StatusServiceUnavailable, get(t, mux, "/readyz"))
+
+	checker.ready = nil
+	assert.Equal(t, http.StatusOK, get(t, mux, "/readyz"))
+
+	checker.live = fmt.Errorf("watch terminated")
+	assert.Equal(t, http.StatusServiceUnavailable, get(t, mux, "/healthz"))
+}
+
+func TestGRPC(t *testing.T) {
+	checker := &testChecker{ready: fmt.Errorf("not ready")}
+	server := &Server{monitor: NewMonitor(checker)}
+	check := func(service string) healthpb.HealthCheckResponse_ServingStatus {
+		response, err := server.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+		assert.NoError(t, err)
+		return response.Status
+	}
+
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, check(""))
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, check(ReadinessService))
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, check(LivenessService))
+
+	checker.ready = nil
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, check(""))
+
+	_, err := server.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "unknown"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+// testWatchServer records the statuses sent on a health watch
+type testWatchServer struct {
+	grpc.ServerStream
+	statuses chan healthpb.HealthCheckResponse_ServingStatus
+}
+
+func (s *testWatchServer) Send(response *healthpb.HealthCheckResponse) error {
+	s.statuses <- response.Status
+	return nil
+}
+
+func (s *testWatchServer) Context() context.Context {
+	return context.Background()
+}
+
+func TestShutdown(t *testing.T) {
+	monitor := NewMonitor(&testChecker{})
+	mux := http.NewServeMux()
+	monitor.RegisterRoutes(mux)
+	server := &Server{monitor: monitor}
+	watch := &testWatchServer{statuses: make(chan healthpb.HealthCheckResponse_ServingStatus, 10)}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.Watch(&healthpb.HealthCheckRequest{}, watch)
+	}()
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, <-watch.statuses)
+
+	monitor.Shutdown()
+	assert.Equal(t, http.StatusServiceUnavailable, get(t, mux, "/readyz"))
+	assert.Equal(t, http.StatusOK, get(t, mux, "/healthz"))
+
+	// The watch reports the proxy is no longer serving before ending
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, <-watch.statuses)
+	select {
+	case err := <-errCh:
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+	case <-time.After(5 * time.Second):
+		t.Fatal("health watch not ended on shutdown")
+	}
+}
@@ -0,0 +1,560 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package manager
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/onosproject/onos-lib-go/pkg/logging"
+	"github.com/onosproject/onos-proxy/pkg/authz"
+	e2v1beta1service "github.com/onosproject/onos-proxy/pkg/e2/v1beta1"
+	"github.com/onosproject/onos-proxy/pkg/utils/creds"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// DefaultGRPCPort is the default port of the northbound gRPC server
+	DefaultGRPCPort = 5151
+	// DefaultHTTPPort is the default port of the HTTP server exposing metrics and health endpoints
+	DefaultHTTPPort = 7070
+	// DefaultE2TAddress is the default address of the E2T service
+	DefaultE2TAddress = "onos-e2t:5150"
+	// DefaultTopoAddress is the default address of the topo service
+	DefaultTopoAddress = "onos-topo:5150"
+	// DefaultLogLevel is the default root logger level
+	DefaultLogLevel = "info"
+	// DefaultMasterPolicy is the default policy for requests targeting E2 nodes with no known master
+	DefaultMasterPolicy = string(e2v1beta1service.WaitForMaster)
+	// DefaultMasterWaitTimeout is the default maximum time to wait for an E2 node master, which bounds the wait of
+	// requests without a deadline
+	DefaultMasterWaitTimeout = 30 * time.Second
+	// DefaultBufferSize is the default number of indications buffered for each subscription stream
+	DefaultBufferSize = e2v1beta1service.DefaultBufferSize
+	// DefaultOverflowPolicy is the default policy for indications of subscription streams whose buffer is full
+	DefaultOverflowPolicy = string(e2v1beta1service.Block)
+	// DefaultSocketMode is the default file mode of the northbound Unix domain socket
+	DefaultSocketMode = "0660"
+	// DefaultShutdownTimeout is the default maximum time to wait for in-flight requests to complete on shutdown
+	DefaultShutdownTimeout = 15 * time.Second
+	// DefaultReplaySpeed is the default factor by which the replay of a recording is accelerated
+	DefaultReplaySpeed = 1.0
+
+	// configEnv is the environment variable holding the configuration file path
+	configEnv = "ONOS_PROXY_CONFIG"
+)
+
+// Config is a manager configuration
+type Config struct {
+	CAPath                 string        `yaml:"caPath"`
+	KeyPath                string        `yaml:"keyPath"`
+	CertPath               string        `yaml:"certPath"`
+	GRPCPort               int           `yaml:"grpcPort"`
+	SocketPath             string        `yaml:"socketPath"`
+	SocketMode             string        `yaml:"socketMode"`
+	HTTPPort               int           `yaml:"httpPort"`
+	E2TAddress             string        `yaml:"e2tAddress"`
+	TopoAddress            string        `yaml:"topoAddress"`
+	LogLevel               string        `yaml:"logLevel"`
+	MasterPolicy           string        `yaml:"masterPolicy"`
+	MasterWaitTimeout      time.Duration `yaml:"masterWaitTimeout"`
+	TracingEndpoint        string        `yaml:"tracingEndpoint"`
+	UpstreamInsecure       bool          `yaml:"upstreamInsecure"`
+	ShutdownTimeout        time.Duration `yaml:"shutdownTimeout"`
+	UnsubscribeOnShutdown  bool          `yaml:"unsubscribeOnShutdown"`
+	AuthzPolicy            string        `yaml:"authzPolicy"`
+	MultiplexSubscriptions bool          `yaml:"multiplexSubscriptions"`
+	BufferSize             int           `yaml:"bufferSize"`
+	OverflowPolicy         string        `yaml:"overflowPolicy"`
+	OrphanTimeout          time.Duration `yaml:"orphanTimeout"`
+	StatePath              string        `yaml:"statePath"`
+	RecordPath             string        `yaml:"recordPath"`
+	ReplayPath             string        `yaml:"replayPath"`
+	ReplaySpeed            float64       `yaml:"replaySpeed"`
+}
+
+// DefaultConfig returns the configuration used when no other source overrides a setting
+func DefaultConfig() Config {
+	return Config{
+		GRPCPort:          DefaultGRPCPort,
+		SocketMode:        DefaultSocketMode,
+		HTTPPort:          DefaultHTTPPort,
+		E2TAddress:        DefaultE2TAddress,
+		TopoAddress:       DefaultTopoAddress,
+		LogLevel:          DefaultLogLevel,
+		MasterPolicy:      DefaultMasterPolicy,
+		MasterWaitTimeout: DefaultMasterWaitTimeout,
+		BufferSize:        DefaultBufferSize,
+		OverflowPolicy:    DefaultOverflowPolicy,
+		ShutdownTimeout:   DefaultShutdownTimeout,
+		ReplaySpeed:       DefaultReplaySpeed,
+	}
+}
+
+// configOption binds a configuration setting to its command-line flag and environment variable
+type configOption struct {
+	flag  string
+	env   string
+	usage string
+	value func(c *Config) flag.Value
+}
+
+var configOptions = []configOption{
+	{
+		flag:  "caPath",
+		env:   "ONOS_PROXY_CA_PATH",
+		usage: "path to CA certificate",
+		value: func(c *Config) flag.Value { return (*stringValue)(&c.CAPath) },
+	},
+	{
+		flag:  "keyPath",
+		env:   "ONOS_PROXY_KEY_PATH",
+		usage: "path to client private key",
+		value: func(c *Config) flag.Value { return (*stringValue)(&c.KeyPath) },
+	},
+	{
+		flag:  "certPath",
+		env:   "ONOS_PROXY_CERT_PATH",
+		usage: "path to client certificate",
+		value: func(c *Config) flag.Value { return (*stringValue)(&c.CertPath) },
+	},
+	{
+		flag:  "grpcPort",
+		env:   "ONOS_PROXY_GRPC_PORT",
+		usage: "TCP port of the northbound gRPC server; zero disables it if socketPath is set",
+		value: func(c *Config) flag.Value { return (*intValue)(&c.GRPCPort) },
+	},
+	{
+		flag:  "socketPath",
+		env:   "ONOS_PROXY_SOCKET_PATH",
+		usage: "path of a Unix domain socket to serve the northbound gRPC services on without TLS; empty disables it",
+		value: func(c *Config) flag.Value { return (*stringValue)(&c.SocketPath) },
+	},
+	{
+		flag:  "socketMode",
+		env:   "ONOS_PROXY_SOCKET_MODE",
+		usage: "octal file mode of the Unix domain socket",
+		value: func(c *Config) flag.Value { return (*stringValue)(&c.SocketMode) },
+	},
+	{
+		flag:  "httpPort",
+		env:   "ONOS_PROXY_HTTP_PORT",
+		usage: "port of the HTTP server exposing the /metrics, /healthz and /readyz endpoints; zero disables it",
+		value: func(c *Config) flag.Value { return (*intValue)(&c.HTTPPort) },
+	},
+	{
+		flag:  "e2tAddress",
+		env:   "ONOS_PROXY_E2T_ADDRESS",
+		usage: "address (host:port) of the E2T service",
+		value: func(c *Config) flag.Value { return (*stringValue)(&c.E2TAddress) },
+	},
+	{
+		flag:  "topoAddress",
+		env:   "ONOS_PROXY_TOPO_ADDRESS",
+		usage: "address (host:port) of the topo service",
+		value: func(c *Config) flag.Value { return (*stringValue)(&c.TopoAddress) },
+	},
+	{
+		flag:  "logLevel",
+		env:   "ONOS_PROXY_LOG_LEVEL",
+		usage: "root logger level (debug, info, warn, error)",
+		value: func(c *Config) flag.Value { return (*stringValue)(&c.LogLevel) },
+	},
+	{
+		flag:  "masterPolicy",
+		env:   "ONOS_PROXY_MASTER_POLICY",
+		usage: "default policy for requests targeting E2 nodes with no known master (fail-fast, wait)",
+		value: func(c *Config) flag.Value { return (*stringValue)(&c.MasterPolicy) },
+	},
+	{
+		flag:  "masterWaitTimeout",
+		env:   "ONOS_PROXY_MASTER_WAIT_TIMEOUT",
+		usage: "default maximum time to wait for an E2 node master; zero waits until the request deadline",
+		value: func(c *Config) flag.Value { return (*durationValue)(&c.MasterWaitTimeout) },
+	},
+	{
+		flag:  "tracingEndpoint",
+		env:   "ONOS_PROXY_TRACING_ENDPOINT",
+		usage: "address (host:port) of the OTLP collector to export traces to; empty disables trace export",
+		value: func(c *Config) flag.Value { return (*stringValue)(&c.TracingEndpoint) },
+	},
+	{
+		flag:  "upstreamInsecure",
+		env:   "ONOS_PROXY_UPSTREAM_INSECURE",
+		usage: "connect to the E2T and topo services with the built-in client certificate without verifying their certificates, instead of using caPath, certPath and keyPath; for development only",
+		value: func(c *Config) flag.Value { return (*boolValue)(&c.UpstreamInsecure) },
+	},
+	{
+		flag:  "shutdownTimeout",
+		env:   "ONOS_PROXY_SHUTDOWN_TIMEOUT",
+		usage: "maximum time to wait on shutdown for in-flight requests and subscription streams to complete before closing them",
+		value: func(c *Config) flag.Value { return (*durationValue)(&c.ShutdownTimeout) },
+	},
+	{
+		flag:  "unsubscribeOnShutdown",
+		env:   "ONOS_PROXY_UNSUBSCRIBE_ON_SHUTDOWN",
+		usage: "delete the subscriptions of the streams ended on shutdown, rather than leaving them to E2T for the apps to resume",
+		value: func(c *Config) flag.Value { return (*boolValue)(&c.UnsubscribeOnShutdown) },
+	},
+	{
+		flag:  "authzPolicy",
+		env:   "ONOS_PROXY_AUTHZ_POLICY",
+		usage: "path to YAML authorization policy granting apps access to E2 nodes, service models and RPCs; empty allows all calls",
+		value: func(c *Config) flag.Value { return (*stringValue)(&c.AuthzPolicy) },
+	},
+	{
+		flag:  "multiplexSubscriptions",
+		env:   "ONOS_PROXY_MULTIPLEX_SUBSCRIPTIONS",
+		usage: "share a single E2T subscription among identical subscribe requests, deleting it once the last subscriber leaves",
+		value: func(c *Config) flag.Value { return (*boolValue)(&c.MultiplexSubscriptions) },
+	},
+	{
+		flag:  "bufferSize",
+		env:   "ONOS_PROXY_BUFFER_SIZE",
+		usage: "number of indications buffered for each subscription stream before the overflow policy applies",
+		value: func(c *Config) flag.Value { return (*intValue)(&c.BufferSize) },
+	},
+	{
+		flag:  "overflowPolicy",
+		env:   "ONOS_PROXY_OVERFLOW_POLICY",
+		usage: "policy for indications of subscription streams whose buffer is full (block, drop-oldest, drop-newest or disconnect)",
+		value: func(c *Config) flag.Value { return (*stringValue)(&c.OverflowPolicy) },
+	},
+	{
+		flag:  "orphanTimeout",
+		env:   "ONOS_PROXY_ORPHAN_TIMEOUT",
+		usage: "time after which subscriptions whose app streams were cancelled without unsubscribing are deleted from E2T; 0 leaves them to the apps",
+		value: func(c *Config) flag.Value { return (*durationValue)(&c.OrphanTimeout) },
+	},
+	{
+		flag:  "statePath",
+		env:   "ONOS_PROXY_STATE_PATH",
+		usage: "path of the file the forwarded subscriptions are persisted to, on a volume outliving the container, so that they are reconciled with E2T on restart; empty disables persistence",
+		value: func(c *Config) flag.Value { return (*stringValue)(&c.StatePath) },
+	},
+	{
+		flag:  "recordPath",
+		env:   "ONOS_PROXY_RECORD_PATH",
+		usage: "path of the file the responses of the forwarded subscriptions are recorded to, for replay; empty disables recording",
+		value: func(c *Config) flag.Value { return (*stringValue)(&c.RecordPath) },
+	},
+	{
+		flag:  "replayPath",
+		env:   "ONOS_PROXY_REPLAY_PATH",
+		usage: "path of a recording to serve subscriptions from instead of forwarding them to E2T",
+		value: func(c *Config) flag.Value { return (*stringValue)(&c.ReplayPath) },
+	},
+	{
+		flag:  "replaySpeed",
+		env:   "ONOS_PROXY_REPLAY_SPEED",
+		usage: "factor by which the replay of the recording is accelerated; 1 replays in real time",
+		value: func(c *Config) flag.Value { return (*floatValue)(&c.ReplaySpeed) },
+	},
+}
+
+// ParseConfig builds the manager configuration from the given command-line arguments, the environment
+// and an optional YAML configuration file. Settings are merged with the following precedence, highest first:
+// command-line flags, environment variables, configuration file, defaults.
+func ParseConfig(name string, args []string, lookupEnv func(string) (string, bool)) (Config, error) {
+	// Parse the flags into a scratch configuration; only the explicitly set ones are applied later
+	var flagConfig Config
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	configPath := flags.String("config", "", fmt.Sprintf("path to YAML configuration file; may also be set via %s", configEnv))
+	for _, option := range configOptions {
+		flags.Var(option.value(&flagConfig), option.flag, fmt.Sprintf("%s; may also be set via %s", option.usage, option.env))
+	}
+	if err := flags.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	config := DefaultConfig()
+
+	path := *configPath
+	if path == "" {
+		path, _ = lookupEnv(configEnv)
+	}
+	if path != "" {
+		if err := config.load(path); err != nil {
+			return Config{}, err
+		}
+	}
+
+	for _, option := range configOptions {
+		if value, ok := lookupEnv(option.env); ok {
+			if err := option.value(&config).Set(value); err != nil {
+				return Config{}, fmt.Errorf("invalid value %q for %s: %v", value, option.env, err)
+			}
+		}
+	}
+
+	flagValues := make(map[string]flag.Value)
+	for _, option := range configOptions {
+		flagValues[option.flag] = option.value(&config)
+	}
+	flags.Visit(func(f *flag.Flag) {
+		if value, ok := flagValues[f.Name]; ok {
+			_ = value.Set(f.Value.String())
+		}
+	})
+
+	if err := config.Validate(); err != nil {
+		return Config{}, err
+	}
+	return config, nil
+}
+
+// load overlays the settings present in the given YAML file onto the configuration
+func (c *Config) load(path string) error {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("unable to read configuration file: %v", err)
+	}
+	if err := yaml.UnmarshalStrict(bytes, c); err != nil {
+		return fmt.Errorf("unable to parse configuration file %s: %v", path, err)
+	}
+	return nil
+}
+
+// Validate checks that the configuration is usable
+func (c Config) Validate() error {
+	// The northbound server accepts only 16-bit signed port numbers
+	if c.GRPCPort < 0 || c.GRPCPort > math.MaxInt16 {
+		return fmt.Errorf("invalid gRPC port %d: must be between 0 and %d", c.GRPCPort, math.MaxInt16)
+	}
+	if c.GRPCPort == 0 && c.SocketPath == "" {
+		return fmt.Errorf("invalid gRPC port 0: the TCP port may only be disabled if socketPath is set")
+	}
+	if _, err := parseSocketMode(c.SocketMode); err != nil {
+		return err
+	}
+	if c.HTTPPort < 0 || c.HTTPPort > math.MaxUint16 {
+		return fmt.Errorf("invalid HTTP port %d: must be between 0 and %d", c.HTTPPort, math.MaxUint16)
+	}
+	if err := validateAddress("E2T", c.E2TAddress); err != nil {
+		return err
+	}
+	if err := validateAddress("topo", c.TopoAddress); err != nil {
+		return err
+	}
+	if c.TracingEndpoint != "" {
+		if err := validateAddress("tracing", c.TracingEndpoint); err != nil {
+			return err
+		}
+	}
+	if (c.KeyPath == "") != (c.CertPath == "") {
+		return fmt.Errorf("keyPath and certPath must be specified together")
+	}
+	for _, path := range []string{c.CAPath, c.KeyPath, c.CertPath} {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("invalid TLS file: %v", err)
+		}
+	}
+	if !c.UpstreamInsecure {
+		if c.CAPath == "" || c.CertPath == "" {
+			return fmt.Errorf("caPath, certPath and keyPath are required for mutual TLS with the E2T and topo services unless upstreamInsecure is set")
+		}
+		if _, err := creds.NewReloader(c.CAPath, c.CertPath, c.KeyPath); err != nil {
+			return fmt.Errorf("invalid TLS configuration: %v", err)
+		}
+	}
+	if _, err := ParseLogLevel(c.LogLevel); err != nil {
+		return err
+	}
+	if _, err := e2v1beta1service.ParseMasterPolicy(c.MasterPolicy); err != nil {
+		return err
+	}
+	if c.MasterWaitTimeout < 0 {
+		return fmt.Errorf("invalid master wait timeout %s", c.MasterWaitTimeout)
+	}
+	if c.BufferSize < 1 {
+		return fmt.Errorf("invalid buffer size %d", c.BufferSize)
+	}
+	if _, err := e2v1beta1service.ParseOverflowPolicy(c.OverflowPolicy); err != nil {
+		return err
+	}
+	if c.OrphanTimeout < 0 {
+		return fmt.Errorf("invalid orphan timeout %s", c.OrphanTimeout)
+	}
+	if c.StatePath != "" {
+		if info, err := os.Stat(filepath.Dir(c.StatePath)); err != nil {
+			return fmt.Errorf("invalid state path: %v", err)
+		} else if !info.IsDir() {
+			return fmt.Errorf("invalid state path: %s is not a directory", filepath.Dir(c.StatePath))
+		}
+	}
+	if c.RecordPath != "" {
+		if info, err := os.Stat(filepath.Dir(c.RecordPath)); err != nil {
+			return fmt.Errorf("invalid record path: %v", err)
+		} else if !info.IsDir() {
+			return fmt.Errorf("invalid record path: %s is not a directory", filepath.Dir(c.RecordPath))
+		}
+	}
+	if c.ReplayPath != "" {
+		if _, err := os.Stat(c.ReplayPath); err != nil {
+			return fmt.Errorf("invalid replay path: %v", err)
+		}
+		// Replayed subscriptions are not forwarded to E2T, so there is nothing to record or reconcile
+		if c.RecordPath != "" || c.StatePath != "" {
+			return fmt.Errorf("replayPath cannot be combined with recordPath or statePath")
+		}
+	}
+	if c.ReplaySpeed <= 0 {
+		return fmt.Errorf("invalid replay speed %g", c.ReplaySpeed)
+	}
+	if c.ShutdownTimeout < 0 {
+		return fmt.Errorf("invalid shutdown timeout %s", c.ShutdownTimeout)
+	}
+	if c.AuthzPolicy != "" {
+		if _, err := authz.LoadPolicy(c.AuthzPolicy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseSocketMode parses the given octal file mode of the Unix domain socket
+func parseSocketMode(mode string) (os.FileMode, error) {
+	m, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil || m > uint64(os.ModePerm) {
+		return 0, fmt.Errorf("invalid socket mode %q: must be octal permission bits, e.g. %s", mode, DefaultSocketMode)
+	}
+	return os.FileMode(m), nil
+}
+
+func validateAddress(service string, address string) error {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("invalid %s address %q: %v", service, address, err)
+	}
+	if host == "" {
+		return fmt.Errorf("invalid %s address %q: missing host", service, address)
+	}
+	if p, err := strconv.Atoi(port); err != nil || p <= 0 || p > math.MaxUint16 {
+		return fmt.Errorf("invalid %s address %q: invalid port", service, address)
+	}
+	return nil
+}
+
+// ParseLogLevel parses the given logger level name
+func ParseLogLevel(level string) (logging.Level, error) {
+	switch strings.ToUpper(level) {
+	case logging.DebugLevel.String():
+		return logging.DebugLevel, nil
+	case logging.InfoLevel.String():
+		return logging.InfoLevel, nil
+	case logging.WarnLevel.String():
+		return logging.WarnLevel, nil
+	case logging.ErrorLevel.String():
+		return logging.ErrorLevel, nil
+	case logging.FatalLevel.String():
+		return logging.FatalLevel, nil
+	case logging.PanicLevel.String():
+		return logging.PanicLevel, nil
+	case logging.DPanicLevel.String():
+		return logging.DPanicLevel, nil
+	}
+	return logging.EmptyLevel, fmt.Errorf("invalid log level %q", level)
+}
+
+type stringValue string
+
+func (v *stringValue) Set(s string) error {
+	*v = stringValue(s)
+	return nil
+}
+
+func (v *stringValue) String() string {
+	if v == nil {
+		return ""
+	}
+	return string(*v)
+}
+
+type boolValue bool
+
+func (v *boolValue) Set(s string) error {
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	*v = boolValue(b)
+	return nil
+}
+
+func (v *boolValue) String() string {
+	if v == nil {
+		return "false"
+	}
+	return strconv.FormatBool(bool(*v))
+}
+
+// IsBoolFlag allows the flag to be given without a value
+func (v *boolValue) IsBoolFlag() bool {
+	return true
+}
+
+type intValue int
+
+func (v *intValue) Set(s string) error {
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		return err
+	}
+	*v = intValue(i)
+	return nil
+}
+
+func (v *intValue) String() string {
+	if v == nil {
+		return "0"
+	}
+	return strconv.Itoa(int(*v))
+}
+
+type floatValue float64
+
+func (v *floatValue) Set(s string) error {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return err
+	}
+	*v = floatValue(f)
+	return nil
+}
+
+func (v *floatValue) String() string {
+	if v == nil {
+		return "0"
+	}
+	return strconv.FormatFloat(float64(*v), 'g', -1, 64)
+}
+
+type durationValue time.Duration
+
+func (v *durationValue) Set(s string) error {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*v = durationValue(d)
+	return nil
+}
+
+func (v *durationValue) String() string {
+	if v == nil {
+		return "0s"
+	}
+	return time.Duration(*v).String()
+}
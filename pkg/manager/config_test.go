@@ -0,0 +1,252 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package manager
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/onosproject/onos-proxy/pkg/harness"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func envMap(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+}
+
+func TestDefaultConfig(t *testing.T) {
+	// Upstream mutual TLS is required by default
+	_, err := ParseConfig("test", nil, envMap(nil))
+	assert.Error(t, err)
+
+	config, err := ParseConfig("test", []string{"-upstreamInsecure"}, envMap(nil))
+	assert.NoError(t, err)
+	expected := DefaultConfig()
+	expected.UpstreamInsecure = true
+	assert.Equal(t, expected, config)
+	assert.Equal(t, 5151, config.GRPCPort)
+	assert.Equal(t, 7070, config.HTTPPort)
+	assert.Equal(t, "onos-e2t:5150", config.E2TAddress)
+	assert.Equal(t, "onos-topo:5150", config.TopoAddress)
+	assert.Equal(t, 15*time.Second, config.ShutdownTimeout)
+	assert.Equal(t, "wait", config.MasterPolicy)
+	assert.Equal(t, 30*time.Second, config.MasterWaitTimeout)
+	assert.False(t, config.UnsubscribeOnShutdown)
+	assert.Empty(t, config.SocketPath)
+	assert.Equal(t, "0660", config.SocketMode)
+	assert.Empty(t, config.AuthzPolicy)
+	assert.False(t, config.MultiplexSubscriptions)
+	assert.Equal(t, 1024, config.BufferSize)
+	assert.Equal(t, "block", config.OverflowPolicy)
+	assert.Zero(t, config.OrphanTimeout)
+	assert.Empty(t, config.StatePath)
+	assert.Empty(t, config.RecordPath)
+	assert.Empty(t, config.ReplayPath)
+	assert.Equal(t, 1.0, config.ReplaySpeed)
+}
+
+func TestSocketConfig(t *testing.T) {
+	// The TCP port may only be disabled when serving on a socket
+	_, err := ParseConfig("test", []string{"-upstreamInsecure", "-grpcPort", "0"}, envMap(nil))
+	assert.Error(t, err)
+
+	env := map[string]string{
+		"ONOS_PROXY_SOCKET_PATH": "/var/run/onos-proxy/proxy.sock",
+		"ONOS_PROXY_SOCKET_MODE": "0666",
+	}
+	config, err := ParseConfig("test", []string{"-upstreamInsecure", "-grpcPort", "0"}, envMap(env))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, config.GRPCPort)
+	assert.Equal(t, "/var/run/onos-proxy/proxy.sock", config.SocketPath)
+	assert.Equal(t, "0666", config.SocketMode)
+}
+
+func TestAuthzPolicyConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "policy.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(`
+apps:
+- name: kpimon
+  identities: [onos-kpimon]
+  serviceModels: [oran-e2sm-kpm]
+`), 0644))
+	config, err := ParseConfig("test", []string{"-upstreamInsecure", "-authzPolicy", path}, envMap(nil))
+	assert.NoError(t, err)
+	assert.Equal(t, path, config.AuthzPolicy)
+
+	// Policies are loaded at startup so that missing or invalid files are rejected
+	_, err = ParseConfig("test", []string{"-upstreamInsecure", "-authzPolicy", filepath.Join(dir, "missing.yaml")}, envMap(nil))
+	assert.Error(t, err)
+	assert.NoError(t, os.WriteFile(path, []byte("apps:\n- name: kpimon\n"), 0644))
+	_, err = ParseConfig("test", nil, envMap(map[string]string{"ONOS_PROXY_UPSTREAM_INSECURE": "true", "ONOS_PROXY_AUTHZ_POLICY": path}))
+	assert.Error(t, err)
+}
+
+func TestConfigPrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "onos-proxy.yaml")
+	err := os.WriteFile(path, []byte(`
+grpcPort: 6000
+e2tAddress: e2t.file:5150
+topoAddress: topo.file:5150
+logLevel: warn
+masterWaitTimeout: 5s
+shutdownTimeout: 20s
+multiplexSubscriptions: true
+bufferSize: 100
+overflowPolicy: disconnect
+orphanTimeout: 1m
+replaySpeed: 2
+`), 0644)
+	assert.NoError(t, err)
+
+	env := map[string]string{
+		"ONOS_PROXY_CONFIG":                  path,
+		"ONOS_PROXY_E2T_ADDRESS":             "e2t.env:5150",
+		"ONOS_PROXY_TOPO_ADDRESS":            "topo.env:5150",
+		"ONOS_PROXY_UPSTREAM_INSECURE":       "true",
+		"ONOS_PROXY_SHUTDOWN_TIMEOUT":        "30s",
+		"ONOS_PROXY_UNSUBSCRIBE_ON_SHUTDOWN": "true",
+		"ONOS_PROXY_OVERFLOW_POLICY":         "drop-oldest",
+		"ONOS_PROXY_ORPHAN_TIMEOUT":          "30s",
+		"ONOS_PROXY_STATE_PATH":              filepath.Join(filepath.Dir(path), "state.json"),
+		"ONOS_PROXY_RECORD_PATH":             filepath.Join(filepath.Dir(path), "recording.bin"),
+		"ONOS_PROXY_REPLAY_SPEED":            "10",
+	}
+	config, err := ParseConfig("test", []string{"-topoAddress", "topo.flag:5150", "-shutdownTimeout", "1m"}, envMap(env))
+	assert.NoError(t, err)
+	assert.Equal(t, 6000, config.GRPCPort)
+	assert.Equal(t, "warn", config.LogLevel)
+	assert.Equal(t, "e2t.env:5150", config.E2TAddress)
+	assert.Equal(t, "topo.flag:5150", config.TopoAddress)
+	assert.Equal(t, 5*time.Second, config.MasterWaitTimeout)
+	assert.Equal(t, "wait", config.MasterPolicy)
+	assert.Equal(t, time.Minute, config.ShutdownTimeout)
+	assert.True(t, config.UnsubscribeOnShutdown)
+	assert.True(t, config.MultiplexSubscriptions)
+	assert.Equal(t, 100, config.BufferSize)
+	assert.Equal(t, "drop-oldest", config.OverflowPolicy)
+	assert.Equal(t, 30*time.Second, config.OrphanTimeout)
+	assert.Equal(t, filepath.Join(filepath.Dir(path), "state.json"), config.StatePath)
+	assert.Equal(t, filepath.Join(filepath.Dir(path), "recording.bin"), config.RecordPath)
+	assert.Equal(t, 10.0, config.ReplaySpeed)
+}
+
+func TestConfigValidation(t *testing.T) {
+	_, err := ParseConfig("test", []string{"-grpcPort", "0"}, envMap(nil))
+	assert.Error(t, err)
+
+	_, err = ParseConfig("test", []string{"-httpPort", "-1"}, envMap(nil))
+	assert.Error(t, err)
+
+	_, err = ParseConfig("test", []string{"-e2tAddress", "onos-e2t"}, envMap(nil))
+	assert.Error(t, err)
+
+	_, err = ParseConfig("test", nil, envMap(map[string]string{"ONOS_PROXY_GRPC_PORT": "abc"}))
+	assert.Error(t, err)
+
+	_, err = ParseConfig("test", []string{"-tracingEndpoint", "otel-collector"}, envMap(nil))
+	assert.Error(t, err)
+
+	_, err = ParseConfig("test", []string{"-keyPath", "/tmp/key.pem"}, envMap(nil))
+	assert.Error(t, err)
+
+	_, err = ParseConfig("test", []string{"-logLevel", "verbose"}, envMap(nil))
+	assert.Error(t, err)
+
+	_, err = ParseConfig("test", []string{"-masterPolicy", "retry"}, envMap(nil))
+	assert.Error(t, err)
+
+	_, err = ParseConfig("test", []string{"-masterWaitTimeout", "-1s"}, envMap(nil))
+	assert.Error(t, err)
+
+	_, err = ParseConfig("test", []string{"-upstreamInsecure=maybe"}, envMap(nil))
+	assert.Error(t, err)
+
+	_, err = ParseConfig("test", []string{"-upstreamInsecure", "-shutdownTimeout", "-1s"}, envMap(nil))
+	assert.Error(t, err)
+
+	_, err = ParseConfig("test", []string{"-upstreamInsecure", "-bufferSize", "0"}, envMap(nil))
+	assert.Error(t, err)
+
+	_, err = ParseConfig("test", []string{"-upstreamInsecure", "-overflowPolicy", "drop-all"}, envMap(nil))
+	assert.Error(t, err)
+
+	_, err = ParseConfig("test", []string{"-upstreamInsecure", "-orphanTimeout", "-1s"}, envMap(nil))
+	assert.Error(t, err)
+
+	_, err = ParseConfig("test", []string{"-upstreamInsecure", "-statePath", "/nonexistent/onos-proxy/state.json"}, envMap(nil))
+	assert.Error(t, err)
+
+	_, err = ParseConfig("test", []string{"-upstreamInsecure", "-recordPath", "/nonexistent/onos-proxy/recording.bin"}, envMap(nil))
+	assert.Error(t, err)
+
+	_, err = ParseConfig("test", []string{"-upstreamInsecure", "-replayPath", "/nonexistent/onos-proxy/recording.bin"}, envMap(nil))
+	assert.Error(t, err)
+
+	recording := filepath.Join(t.TempDir(), "recording.bin")
+	require.NoError(t, os.WriteFile(recording, nil, 0644))
+	_, err = ParseConfig("test", []string{"-upstreamInsecure", "-replayPath", recording}, envMap(nil))
+	assert.NoError(t, err)
+
+	_, err = ParseConfig("test", []string{"-upstreamInsecure", "-replayPath", recording, "-recordPath", recording}, envMap(nil))
+	assert.Error(t, err)
+
+	_, err = ParseConfig("test", []string{"-upstreamInsecure", "-replaySpeed", "0"}, envMap(nil))
+	assert.Error(t, err)
+
+	_, err = ParseConfig("test", []string{"-upstreamInsecure", "-replaySpeed", "fast"}, envMap(nil))
+	assert.Error(t, err)
+
+	_, err = ParseConfig("test", []string{"-upstreamInsecure", "-socketPath", "/tmp/proxy.sock", "-socketMode", "0999"}, envMap(nil))
+	assert.Error(t, err)
+
+	_, err = ParseConfig("test", []string{"-upstreamInsecure", "-socketPath", "/tmp/proxy.sock", "-socketMode", "01777"}, envMap(nil))
+	assert.Error(t, err)
+
+	path := filepath.Join(t.TempDir(), "onos-proxy.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte("unknownKey: true\n"), 0644))
+	_, err = ParseConfig("test", []string{"-config", path}, envMap(nil))
+	assert.Error(t, err)
+}
+
+func TestUpstreamTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	certs, err := harness.NewCertificates(dir, "onos-e2t", "onos-topo")
+	require.NoError(t, err)
+
+	args := []string{"-caPath", certs.CAPath, "-certPath", certs.ClientCertPath, "-keyPath", certs.ClientKeyPath}
+	config, err := ParseConfig("test", args, envMap(nil))
+	assert.NoError(t, err)
+	assert.False(t, config.UpstreamInsecure)
+	mgr := NewManager(config)
+	require.NoError(t, mgr.startCertReloader())
+	defer mgr.certReloader.Close()
+	transportCreds, err := mgr.upstreamCredentials()
+	require.NoError(t, err)
+	assert.Equal(t, "tls", transportCreds.Info().SecurityProtocol)
+
+	// The CA is required to verify the servers
+	_, err = ParseConfig("test", args[2:], envMap(nil))
+	assert.Error(t, err)
+
+	// Mismatched certificate and key files are rejected at startup
+	_, err = ParseConfig("test", []string{"-caPath", certs.CAPath, "-certPath", certs.ClientCertPath, "-keyPath", certs.ServerKeyPath}, envMap(nil))
+	assert.Error(t, err)
+	_, err = ParseConfig("test", []string{"-caPath", certs.ClientKeyPath, "-certPath", certs.ClientCertPath, "-keyPath", certs.ClientKeyPath}, envMap(nil))
+	assert.Error(t, err)
+
+	// The TLS files may be omitted when insecure upstream connections are explicitly enabled
+	path := filepath.Join(dir, "onos-proxy.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte("upstreamInsecure: true\n"), 0644))
+	config, err = ParseConfig("test", []string{"-config", path}, envMap(nil))
+	assert.NoError(t, err)
+	assert.True(t, config.UpstreamInsecure)
+}
@@ -6,14 +6,28 @@ package manager
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
 
 	"github.com/onosproject/onos-lib-go/pkg/grpc/retry"
 	"github.com/onosproject/onos-lib-go/pkg/logging"
 	"github.com/onosproject/onos-lib-go/pkg/northbound"
+	"github.com/onosproject/onos-proxy/pkg/admin"
+	"github.com/onosproject/onos-proxy/pkg/authz"
 	e2v1beta1service "github.com/onosproject/onos-proxy/pkg/e2/v1beta1"
 	"github.com/onosproject/onos-proxy/pkg/e2/v1beta1/balancer"
+	"github.com/onosproject/onos-proxy/pkg/health"
+	"github.com/onosproject/onos-proxy/pkg/topo"
+	"github.com/onosproject/onos-proxy/pkg/tracing"
 	"github.com/onosproject/onos-proxy/pkg/utils/creds"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
@@ -21,14 +35,6 @@ import (
 
 var log = logging.GetLogger()
 
-// Config is a manager configuration
-type Config struct {
-	CAPath   string
-	KeyPath  string
-	CertPath string
-	GRPCPort int
-}
-
 // NewManager creates a new manager
 func NewManager(config Config) *Manager {
 	log.Info("Creating Manager")
@@ -37,12 +43,33 @@ func NewManager(config Config) *Manager {
 	}
 }
 
+// unsubscribeTimeout is the maximum time to wait for subscriptions to be deleted on shutdown
+const unsubscribeTimeout = 5 * time.Second
+
+// northboundServer is a gRPC server serving the northbound services
+type northboundServer interface {
+	GracefulStop()
+	Stop()
+}
+
 // Manager is a manager for the E2T service
 type Manager struct {
-	Config Config
+	Config          Config
+	servers         []northboundServer
+	proxyService    *e2v1beta1service.SubscriptionService
+	monitor         *health.Monitor
+	e2tConn         *grpc.ClientConn
+	topoConn        *grpc.ClientConn
+	topoCache       *topo.Cache
+	httpServer      *http.Server
+	certReloader    *creds.Reloader
+	policy          *authz.Policy
+	recorder        *e2v1beta1service.Recorder
+	shutdownTracing func(context.Context) error
 }
 
-// Run starts the manager and the associated services
+// Run starts the manager and the associated services. Only local failures such as unusable certificates or an
+// unavailable port are fatal; the E2T and topo services need not be up yet.
 func (m *Manager) Run() {
 	log.Info("Running Manager")
 	if err := m.Start(); err != nil {
@@ -52,15 +79,108 @@ func (m *Manager) Run() {
 
 // Start starts the manager
 func (m *Manager) Start() error {
-	err := m.startNorthboundServer()
+	shutdownTracing, err := tracing.Init(context.Background(), m.Config.TracingEndpoint)
+	if err != nil {
+		return err
+	}
+	m.shutdownTracing = shutdownTracing
+
+	err = m.startNorthboundServer()
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-// startSouthboundServer starts the northbound gRPC server
+// startNorthboundServer starts serving the northbound gRPC services on the TCP port and the Unix domain socket,
+// as configured
 func (m *Manager) startNorthboundServer() error {
+	if err := m.startCertReloader(); err != nil {
+		return err
+	}
+	if m.Config.AuthzPolicy != "" {
+		policy, err := authz.LoadPolicy(m.Config.AuthzPolicy)
+		if err != nil {
+			return err
+		}
+		log.Infof("Authorizing calls with policy %s", m.Config.AuthzPolicy)
+		m.policy = policy
+	}
+	transportCreds, err := m.upstreamCredentials()
+	if err != nil {
+		return err
+	}
+	var recording *e2v1beta1service.Recording
+	if m.Config.ReplayPath != "" {
+		if recording, err = e2v1beta1service.LoadRecording(m.Config.ReplayPath); err != nil {
+			return err
+		}
+		log.Infof("Serving subscriptions from recording %s", m.Config.ReplayPath)
+	}
+	if m.Config.RecordPath != "" {
+		if m.recorder, err = e2v1beta1service.NewRecorder(m.Config.RecordPath); err != nil {
+			return err
+		}
+		log.Infof("Recording subscriptions to %s", m.Config.RecordPath)
+	}
+
+	resolverBuilder := balancer.NewResolverBuilder(m.Config.TopoAddress)
+	if err := prometheus.Register(balancer.NewCollector(resolverBuilder)); err != nil {
+		return err
+	}
+	// The upstream connections are established in the background; until they are, the proxy serves but
+	// reports that it is not ready, and requests wait for or fail on the E2 node masters per the master policy
+	m.e2tConn, err = m.connect(context.Background(), resolverBuilder, transportCreds)
+	if err != nil {
+		log.Errorf("Unable to connect to E2T service")
+		return err
+	}
+
+	m.topoConn, err = m.connectTopo(context.Background(), transportCreds)
+	if err != nil {
+		log.Errorf("Unable to connect to topo service")
+		return err
+	}
+	m.topoCache = topo.NewCache(m.topoConn)
+	m.topoCache.Start()
+
+	m.monitor = health.NewMonitor(resolverBuilder, m.topoCache)
+	m.startHTTPServer(m.monitor)
+
+	m.proxyService = e2v1beta1service.NewProxyService(m.e2tConn, resolverBuilder, e2v1beta1service.Options{
+		MasterPolicy:           e2v1beta1service.MasterPolicy(m.Config.MasterPolicy),
+		MasterWaitTimeout:      m.Config.MasterWaitTimeout,
+		MultiplexSubscriptions: m.Config.MultiplexSubscriptions,
+		BufferSize:             m.Config.BufferSize,
+		OverflowPolicy:         e2v1beta1service.OverflowPolicy(m.Config.OverflowPolicy),
+		OrphanTimeout:          m.Config.OrphanTimeout,
+		StatePath:              m.Config.StatePath,
+		Recorder:               m.recorder,
+		Recording:              recording,
+		ReplaySpeed:            m.Config.ReplaySpeed,
+	})
+	services := []northbound.Service{
+		logging.Service{},
+		health.NewService(m.monitor),
+		admin.NewService(resolverBuilder, m.proxyService),
+		m.proxyService,
+		topo.NewProxyService(m.topoConn, m.topoCache),
+	}
+	if m.Config.GRPCPort != 0 {
+		if err := m.startTCPServer(services); err != nil {
+			return err
+		}
+	}
+	if m.Config.SocketPath != "" {
+		if err := m.startSocketServer(services); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// startTCPServer starts serving the given services with TLS on the gRPC port
+func (m *Manager) startTCPServer(services []northbound.Service) error {
 	s := northbound.NewServer(&northbound.ServerConfig{
 		CaPath:      &m.Config.CAPath,
 		KeyPath:     &m.Config.KeyPath,
@@ -69,22 +189,17 @@ func (m *Manager) startNorthboundServer() error {
 		Insecure:    true,
 		SecurityCfg: &northbound.SecurityConfig{},
 	})
-
-	conn, err := m.connect(context.Background())
-	if err != nil {
-		log.Errorf("Unable to connect to E2T service")
-		return err
+	for _, service := range services {
+		s.AddService(service)
 	}
 
-	s.AddService(logging.Service{})
-	s.AddService(e2v1beta1service.NewProxyService(conn))
-
 	doneCh := make(chan error)
 	go func() {
 		err := s.Serve(func(started string) {
 			log.Info("Started NBI on ", started)
+			m.servers = append(m.servers, s)
 			close(doneCh)
-		})
+		}, m.tcpServerOptions()...)
 		if err != nil {
 			doneCh <- err
 		}
@@ -92,12 +207,158 @@ func (m *Manager) startNorthboundServer() error {
 	return <-doneCh
 }
 
-func (m *Manager) connect(ctx context.Context) (*grpc.ClientConn, error) {
-	clientCreds, _ := creds.GetClientCredentials()
-	conn, err := grpc.DialContext(ctx, fmt.Sprintf("%s:///%s", balancer.ResolverName, "onos-e2t:5150"),
-		grpc.WithTransportCredentials(credentials.NewTLS(clientCreds)),
-		grpc.WithUnaryInterceptor(retry.RetryingUnaryClientInterceptor(retry.WithRetryOn(codes.Unavailable))),
-		grpc.WithStreamInterceptor(retry.RetryingStreamClientInterceptor(retry.WithRetryOn(codes.Unavailable))))
+// startSocketServer starts serving the given services on the Unix domain socket. The socket is only reachable
+// from within the pod and access to it is controlled by its file mode, so it is served without TLS.
+func (m *Manager) startSocketServer(services []northbound.Service) error {
+	mode, err := parseSocketMode(m.Config.SocketMode)
+	if err != nil {
+		return err
+	}
+	lis, err := listenUnix(m.Config.SocketPath, mode)
+	if err != nil {
+		return err
+	}
+	s := grpc.NewServer(m.serverOptions()...)
+	for _, service := range services {
+		service.Register(s)
+	}
+	m.servers = append(m.servers, s)
+	go func() {
+		if err := s.Serve(lis); err != nil {
+			log.Errorf("Unable to serve NBI on %s: %v", m.Config.SocketPath, err)
+		}
+	}()
+	log.Infof("Started NBI on unix socket %s", m.Config.SocketPath)
+	return nil
+}
+
+// listenUnix listens on the Unix domain socket at the given path with the given file mode, replacing any socket
+// left behind by a previous run of the proxy
+func listenUnix(path string, mode os.FileMode) (net.Listener, error) {
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("unable to listen on %s: file exists and is not a socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	lis, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		_ = lis.Close()
+		return nil, err
+	}
+	return lis, nil
+}
+
+// startHTTPServer starts the HTTP server exposing Prometheus metrics and the health endpoints, unless disabled
+func (m *Manager) startHTTPServer(monitor *health.Monitor) {
+	if m.Config.HTTPPort == 0 {
+		return
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	monitor.RegisterRoutes(mux)
+	m.httpServer = &http.Server{
+		Addr:    fmt.Sprintf(":%d", m.Config.HTTPPort),
+		Handler: mux,
+	}
+	go func() {
+		log.Infof("Serving HTTP on %s", m.httpServer.Addr)
+		if err := m.httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorf("Unable to serve HTTP: %v", err)
+		}
+	}()
+}
+
+// upstreamCredentials returns the transport credentials for the E2T and topo connections. Unless insecure
+// upstream connections are enabled, the proxy authenticates with the configured client certificate and verifies
+// the certificate and name of the servers against the configured CA.
+func (m *Manager) upstreamCredentials() (credentials.TransportCredentials, error) {
+	if m.Config.UpstreamInsecure {
+		log.Warn("Upstream connections do not verify the E2T and topo server certificates")
+		clientCreds, err := creds.GetClientCredentials()
+		if err != nil {
+			return nil, err
+		}
+		return credentials.NewTLS(clientCreds), nil
+	}
+	if m.certReloader == nil {
+		return nil, errors.New("caPath, certPath and keyPath are required for upstream mutual TLS")
+	}
+	return credentials.NewTLS(m.certReloader.ClientConfig()), nil
+}
+
+// startCertReloader starts reloading the configured certificate, key and CA files whenever they change,
+// unless they are not all configured
+func (m *Manager) startCertReloader() error {
+	if m.Config.CAPath == "" || m.Config.CertPath == "" || m.Config.KeyPath == "" {
+		return nil
+	}
+	reloader, err := creds.NewReloader(m.Config.CAPath, m.Config.CertPath, m.Config.KeyPath)
+	if err != nil {
+		return err
+	}
+	reloader.Start(creds.DefaultReloadInterval)
+	m.certReloader = reloader
+	return nil
+}
+
+// serverOptions returns the options common to the northbound gRPC servers
+func (m *Manager) serverOptions() []grpc.ServerOption {
+	opts := tracing.ServerOptions()
+	if m.policy != nil {
+		opts = append(opts,
+			grpc.ChainUnaryInterceptor(authz.UnaryServerInterceptor(m.policy)),
+			grpc.ChainStreamInterceptor(authz.StreamServerInterceptor(m.policy)))
+	}
+	return opts
+}
+
+// tcpServerOptions returns the options of the northbound gRPC server serving on the TCP port
+func (m *Manager) tcpServerOptions() []grpc.ServerOption {
+	opts := m.serverOptions()
+	if m.certReloader != nil {
+		// Overrides the credentials loaded once by the northbound server. Client certificates are requested but
+		// optional, as for the insecure northbound server; when authorizing calls, presented certificates must
+		// be issued by the CA so that they can identify apps.
+		clientAuth := tls.RequestClientCert
+		if m.policy != nil {
+			clientAuth = tls.VerifyClientCertIfGiven
+		}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(m.certReloader.ServerConfig(clientAuth))))
+	}
+	return opts
+}
+
+func (m *Manager) connect(ctx context.Context, resolverBuilder *balancer.ResolverBuilder, transportCreds credentials.TransportCredentials) (*grpc.ClientConn, error) {
+	conn, err := grpc.DialContext(ctx, fmt.Sprintf("%s:///%s", balancer.ResolverName, m.Config.E2TAddress),
+		grpc.WithTransportCredentials(transportCreds),
+		grpc.WithResolvers(resolverBuilder),
+		grpc.WithChainUnaryInterceptor(
+			retry.RetryingUnaryClientInterceptor(retry.WithRetryOn(codes.Unavailable)),
+			tracing.UnaryClientInterceptor()),
+		grpc.WithChainStreamInterceptor(
+			retry.RetryingStreamClientInterceptor(retry.WithRetryOn(codes.Unavailable)),
+			tracing.StreamClientInterceptor()))
+	if err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
+func (m *Manager) connectTopo(ctx context.Context, transportCreds credentials.TransportCredentials) (*grpc.ClientConn, error) {
+	conn, err := grpc.DialContext(ctx, m.Config.TopoAddress,
+		grpc.WithTransportCredentials(transportCreds),
+		grpc.WithChainUnaryInterceptor(
+			retry.RetryingUnaryClientInterceptor(retry.WithRetryOn(codes.Unavailable)),
+			tracing.UnaryClientInterceptor()),
+		grpc.WithChainStreamInterceptor(
+			retry.RetryingStreamClientInterceptor(retry.WithRetryOn(codes.Unavailable)),
+			tracing.StreamClientInterceptor()))
 	if err != nil {
 		return nil, err
 	}
@@ -112,7 +373,113 @@ func (m *Manager) Close() {
 	}
 }
 
-// Stop stops the manager
+// Stop shuts the manager down gracefully. The proxy is reported as no longer ready and stops accepting
+// requests, while in-flight requests and subscription streams are given up to the shutdown timeout to
+// complete before they are closed. An error is returned if any had to be closed or if the proxy's connections
+// could not be closed cleanly.
 func (m *Manager) Stop() error {
+	log.Info("Stopping Manager")
+	var errs []error
+	if m.monitor != nil {
+		m.monitor.Shutdown()
+	}
+	if m.proxyService != nil {
+		// The streams closed on shutdown are not orphaned by the apps
+		m.proxyService.Close()
+	}
+	if len(m.servers) > 0 {
+		if err := m.drain(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	// Closing the E2T connection also closes the resolver's connection to topo
+	if m.e2tConn != nil {
+		if err := m.e2tConn.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("unable to close E2T connection: %v", err))
+		}
+	}
+	if m.topoCache != nil {
+		m.topoCache.Close()
+	}
+	if m.recorder != nil {
+		if err := m.recorder.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("unable to close recording: %v", err))
+		}
+	}
+	if m.topoConn != nil {
+		if err := m.topoConn.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("unable to close topo connection: %v", err))
+		}
+	}
+	if m.httpServer != nil {
+		if err := m.httpServer.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("unable to close HTTP server: %v", err))
+		}
+	}
+	if m.shutdownTracing != nil {
+		if err := m.shutdownTracing(context.Background()); err != nil {
+			log.Warnf("Unable to flush traces: %v", err)
+		}
+	}
+	if m.certReloader != nil {
+		m.certReloader.Close()
+	}
+
+	for _, err := range errs {
+		log.Warn(err)
+	}
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	log.Info("Stopped Manager")
 	return nil
 }
+
+// drain stops the northbound servers, ending the subscription streams and, if configured, deleting their
+// subscriptions, then gives in-flight requests up to the shutdown timeout to complete before closing them, and
+// the shared subscriptions of the ended streams as long again to be deleted from E2T
+func (m *Manager) drain() error {
+	stoppedCh := make(chan struct{})
+	go func() {
+		var wg sync.WaitGroup
+		for _, server := range m.servers {
+			wg.Add(1)
+			go func(server northboundServer) {
+				defer wg.Done()
+				server.GracefulStop()
+			}(server)
+		}
+		wg.Wait()
+		close(stoppedCh)
+	}()
+	// Subscription streams last until the apps close them, so the proxy ends them for the apps to resubscribe
+	// through another instance
+	ctx, cancel := context.WithTimeout(context.Background(), unsubscribeTimeout)
+	if err := m.proxyService.CloseStreams(ctx, m.Config.UnsubscribeOnShutdown); err != nil {
+		log.Warnf("Unable to delete subscriptions: %v", err)
+	}
+	cancel()
+
+	var err error
+	timer := time.NewTimer(m.Config.ShutdownTimeout)
+	defer timer.Stop()
+	select {
+	case <-stoppedCh:
+		log.Info("Drained all in-flight requests")
+	case <-timer.C:
+		log.Warnf("Requests still in flight after %s; closing them", m.Config.ShutdownTimeout)
+		for _, server := range m.servers {
+			server.Stop()
+		}
+		<-stoppedCh
+		err = fmt.Errorf("requests still in flight after the %s shutdown timeout were closed", m.Config.ShutdownTimeout)
+	}
+
+	// The shared subscriptions of the ended streams are being deleted from E2T, which needs its connection
+	ctx, cancel = context.WithTimeout(context.Background(), m.Config.ShutdownTimeout)
+	defer cancel()
+	if waitErr := m.proxyService.WaitSharedSubscriptions(ctx); waitErr != nil && err == nil {
+		err = fmt.Errorf("shared subscriptions not deleted within the %s shutdown timeout", m.Config.ShutdownTimeout)
+	}
+	return err
+}
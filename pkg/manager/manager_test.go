@@ -0,0 +1,439 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package manager
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"math"
+	"math/rand"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	e2api "github.com/onosproject/onos-api/go/onos/e2t/e2/v1beta1"
+	"github.com/onosproject/onos-proxy/pkg/harness"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// testUpstream is a fake topo service and a fake E2T instance served over TCP on reserved ports
+type testUpstream struct {
+	topo        *harness.TopoServer
+	e2t         *harness.E2TServer
+	certs       *harness.Certificates
+	topoAddress string
+}
+
+func newTestUpstream(t *testing.T) *testUpstream {
+	certs, err := harness.NewCertificates(t.TempDir(), "localhost")
+	require.NoError(t, err)
+	upstream := &testUpstream{
+		topo:        harness.NewTopoServer(),
+		e2t:         harness.NewE2TServer(fmt.Sprintf("localhost:%d", reservePort(t))),
+		certs:       certs,
+		topoAddress: fmt.Sprintf("localhost:%d", reservePort(t)),
+	}
+	_, e2tPort, err := net.SplitHostPort(upstream.e2t.Address())
+	require.NoError(t, err)
+	port, err := strconv.Atoi(e2tPort)
+	require.NoError(t, err)
+	upstream.topo.AddE2T("e2t-1", "localhost", uint32(port))
+	upstream.topo.SetMaster("e2-1", "e2t-1")
+	return upstream
+}
+
+// start starts serving the topo and E2T services on their reserved ports
+func (u *testUpstream) start(t *testing.T) {
+	serverCreds, err := u.certs.ServerCredentials()
+	require.NoError(t, err)
+	serve(t, u.topoAddress, u.topo.Register, grpc.Creds(serverCreds))
+	serve(t, u.e2t.Address(), u.e2t.Register, grpc.Creds(serverCreds))
+}
+
+// testProxy is a running manager connected to the test upstream services
+type testProxy struct {
+	manager *Manager
+	e2t     *harness.E2TServer
+	conn    *grpc.ClientConn
+}
+
+// newTestProxy starts a manager connected to the given upstream services, with the configuration adjusted by the
+// given function. The proxy is connected to via TCP unless the TCP port is disabled.
+func newTestProxy(t *testing.T, upstream *testUpstream, configure func(config *Config)) *testProxy {
+	// The manager registers its collectors on start; a fresh registry allows a manager per test
+	registerer := prometheus.DefaultRegisterer
+	prometheus.DefaultRegisterer = prometheus.NewRegistry()
+	t.Cleanup(func() {
+		prometheus.DefaultRegisterer = registerer
+	})
+
+	config := DefaultConfig()
+	config.GRPCPort = freePort(t)
+	config.HTTPPort = 0
+	config.E2TAddress = upstream.e2t.Address()
+	config.TopoAddress = upstream.topoAddress
+	config.CAPath = upstream.certs.CAPath
+	config.CertPath = upstream.certs.ClientCertPath
+	config.KeyPath = upstream.certs.ClientKeyPath
+	configure(&config)
+	require.NoError(t, config.Validate())
+
+	proxy := &testProxy{
+		manager: NewManager(config),
+		e2t:     upstream.e2t,
+	}
+	require.NoError(t, proxy.manager.Start())
+	if config.GRPCPort != 0 {
+		proxy.conn = dial(t, fmt.Sprintf("localhost:%d", config.GRPCPort),
+			grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{InsecureSkipVerify: true})))
+	} else {
+		proxy.conn = dial(t, "unix://"+config.SocketPath, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	}
+	return proxy
+}
+
+func dial(t *testing.T, target string, opts ...grpc.DialOption) *grpc.ClientConn {
+	conn, err := grpc.Dial(target, opts...)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+	return conn
+}
+
+// freePort returns a presently free port for the northbound server, which accepts only 16-bit signed port
+// numbers and so cannot be given an ephemeral one
+func freePort(t *testing.T) int {
+	for port := 20000 + rand.Intn(10000); port < math.MaxInt16; port++ {
+		listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+		if err == nil {
+			require.NoError(t, listener.Close())
+			return port
+		}
+	}
+	t.Fatal("no free port")
+	return 0
+}
+
+// reservePort returns a presently free ephemeral port to serve on later
+func reservePort(t *testing.T) int {
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func serve(t *testing.T, address string, register func(*grpc.Server), opts ...grpc.ServerOption) {
+	listener, err := net.Listen("tcp", address)
+	require.NoError(t, err)
+	server := grpc.NewServer(opts...)
+	register(server)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+}
+
+// subscribe opens a subscription stream for E2 node e2-1 via the proxy and waits for its acknowledgement
+func (p *testProxy) subscribe(t *testing.T, ctx context.Context) e2api.SubscriptionService_SubscribeClient {
+	stream, err := e2api.NewSubscriptionServiceClient(p.conn).Subscribe(ctx, &e2api.SubscribeRequest{
+		Headers:       e2api.RequestHeaders{E2NodeID: "e2-1"},
+		TransactionID: "sub-1",
+	}, grpc.WaitForReady(true))
+	require.NoError(t, err)
+	response, err := stream.Recv()
+	require.NoError(t, err)
+	require.NotNil(t, response.GetAck())
+	return stream
+}
+
+func (p *testProxy) stop() <-chan error {
+	stopCh := make(chan error, 1)
+	go func() {
+		stopCh <- p.manager.Stop()
+	}()
+	return stopCh
+}
+
+func TestGracefulShutdown(t *testing.T) {
+	upstream := newTestUpstream(t)
+	upstream.start(t)
+	proxy := newTestProxy(t, upstream, func(config *Config) {
+		config.ShutdownTimeout = 10 * time.Second
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	stream := proxy.subscribe(t, ctx)
+	assert.Equal(t, 1, proxy.e2t.Indicate("e2-1", e2api.Indication{Payload: []byte("1")}))
+	response, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("1"), response.GetIndication().Payload)
+
+	// The proxy ends the open stream for the app to resubscribe elsewhere, without deleting the subscription,
+	// and stops without waiting for the shutdown timeout
+	stopCh := proxy.stop()
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	select {
+	case err := <-stopCh:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("not stopped after the stream was ended")
+	}
+	assert.Error(t, proxy.manager.monitor.Ready())
+	assert.Empty(t, proxy.e2t.Unsubscribes())
+	assert.Equal(t, connectivity.Shutdown, proxy.manager.e2tConn.GetState())
+	assert.Equal(t, connectivity.Shutdown, proxy.manager.topoConn.GetState())
+	assert.Eventually(t, func() bool {
+		return proxy.e2t.Streams() == 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestShutdownUnsubscribe(t *testing.T) {
+	upstream := newTestUpstream(t)
+	upstream.start(t)
+	proxy := newTestProxy(t, upstream, func(config *Config) {
+		config.ShutdownTimeout = 10 * time.Second
+		config.UnsubscribeOnShutdown = true
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	stream := proxy.subscribe(t, ctx)
+
+	// The subscription of the ended stream is deleted
+	stopCh := proxy.stop()
+	_, err := stream.Recv()
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	select {
+	case err := <-stopCh:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("not stopped after the stream was ended")
+	}
+	unsubscribes := proxy.e2t.Unsubscribes()
+	if assert.Len(t, unsubscribes, 1) {
+		assert.Equal(t, e2api.TransactionID("sub-1"), unsubscribes[0].TransactionID)
+		assert.Equal(t, e2api.E2NodeID("e2-1"), unsubscribes[0].Headers.E2NodeID)
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	upstream := newTestUpstream(t)
+	upstream.start(t)
+	proxy := newTestProxy(t, upstream, func(config *Config) {
+		config.ShutdownTimeout = 100 * time.Millisecond
+	})
+	// The flow control window of the app is fixed so that it fills up while the app is not reading
+	proxy.conn = dial(t, fmt.Sprintf("localhost:%d", proxy.manager.Config.GRPCPort),
+		grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{InsecureSkipVerify: true})),
+		grpc.WithInitialWindowSize(64*1024), grpc.WithInitialConnWindowSize(64*1024))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	stream := proxy.subscribe(t, ctx)
+	payload := make([]byte, 16*1024)
+	for i := 0; i < 20; i++ {
+		assert.Equal(t, 1, proxy.e2t.Indicate("e2-1", e2api.Indication{Payload: payload}))
+	}
+	assert.Eventually(t, func() bool {
+		subscriptions := proxy.manager.proxyService.Subscriptions()
+		return len(subscriptions) == 1 && subscriptions[0].Indications == 20
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// The stream cannot be ended before the timeout expires since the app is not receiving the indications
+	// buffered for it, so it is closed
+	select {
+	case err := <-proxy.stop():
+		assert.Error(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("not stopped after the shutdown timeout")
+	}
+	for {
+		if _, err := stream.Recv(); err != nil {
+			break
+		}
+	}
+}
+
+func TestStartWithoutUpstream(t *testing.T) {
+	// The proxy starts serving while the topo and E2T services are not yet up, e.g. when the app pod is
+	// scheduled before them, and reports that it is not ready
+	upstream := newTestUpstream(t)
+	proxy := newTestProxy(t, upstream, func(config *Config) {
+		config.ShutdownTimeout = time.Second
+	})
+	defer func() {
+		_ = proxy.manager.Stop()
+	}()
+	assert.NoError(t, proxy.manager.monitor.Live())
+	assert.Error(t, proxy.manager.monitor.Ready())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	controlCh := make(chan error, 1)
+	go func() {
+		_, err := e2api.NewControlServiceClient(proxy.conn).Control(ctx, &e2api.ControlRequest{
+			Headers: e2api.RequestHeaders{E2NodeID: "e2-1"},
+		})
+		controlCh <- err
+	}()
+	time.Sleep(200 * time.Millisecond)
+
+	// Requests waiting for the E2 node master are served once the upstream services come up
+	upstream.start(t)
+	select {
+	case err := <-controlCh:
+		assert.NoError(t, err)
+	case <-ctx.Done():
+		t.Fatal("control request not served after the upstream services came up")
+	}
+	assert.Eventually(t, func() bool {
+		return proxy.manager.monitor.Ready() == nil
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+// control sends a control request for E2 node e2-1 via the given connection
+func control(t *testing.T, conn *grpc.ClientConn) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := e2api.NewControlServiceClient(conn).Control(ctx, &e2api.ControlRequest{
+		Headers: e2api.RequestHeaders{E2NodeID: "e2-1"},
+	}, grpc.WaitForReady(true))
+	assert.NoError(t, err)
+}
+
+func TestSocket(t *testing.T) {
+	upstream := newTestUpstream(t)
+	upstream.start(t)
+	path := filepath.Join(t.TempDir(), "onos-proxy.sock")
+	// A socket left behind by a previous run is replaced
+	stale, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(t, stale.Close())
+
+	proxy := newTestProxy(t, upstream, func(config *Config) {
+		config.GRPCPort = 0
+		config.SocketPath = path
+		config.SocketMode = "0600"
+	})
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	control(t, proxy.conn)
+	assert.Len(t, upstream.e2t.Controls(), 1)
+
+	// The socket is removed on shutdown
+	assert.NoError(t, proxy.manager.Stop())
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestSocketAlongsideTCP(t *testing.T) {
+	upstream := newTestUpstream(t)
+	upstream.start(t)
+	path := filepath.Join(t.TempDir(), "onos-proxy.sock")
+	proxy := newTestProxy(t, upstream, func(config *Config) {
+		config.SocketPath = path
+	})
+	defer func() {
+		_ = proxy.manager.Stop()
+	}()
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0660), info.Mode().Perm())
+
+	control(t, proxy.conn)
+	control(t, dial(t, "unix://"+path, grpc.WithTransportCredentials(insecure.NewCredentials())))
+	assert.Len(t, upstream.e2t.Controls(), 2)
+}
+
+func TestSocketNotReplacingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "onos-proxy.sock")
+	require.NoError(t, os.WriteFile(path, nil, 0644))
+	_, err := listenUnix(path, 0600)
+	assert.Error(t, err)
+}
+
+func TestAuthzPolicy(t *testing.T) {
+	upstream := newTestUpstream(t)
+	upstream.start(t)
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+apps:
+- name: test
+  identities: [onos-proxy-test-client]
+  e2Nodes: [e2-1]
+  rpcs: [Control]
+`), 0644))
+	proxy := newTestProxy(t, upstream, func(config *Config) {
+		config.AuthzPolicy = path
+	})
+	defer func() {
+		_ = proxy.manager.Stop()
+	}()
+	control := func(conn *grpc.ClientConn, nodeID string) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_, err := e2api.NewControlServiceClient(conn).Control(ctx, &e2api.ControlRequest{
+			Headers: e2api.RequestHeaders{E2NodeID: e2api.E2NodeID(nodeID)},
+		}, grpc.WaitForReady(true))
+		return err
+	}
+
+	// Callers without a client certificate have no identity
+	assert.Equal(t, codes.PermissionDenied, status.Code(control(proxy.conn, "e2-1")))
+
+	cert, err := tls.LoadX509KeyPair(upstream.certs.ClientCertPath, upstream.certs.ClientKeyPath)
+	require.NoError(t, err)
+	conn := dial(t, proxy.conn.Target(), grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{
+		Certificates:       []tls.Certificate{cert},
+		InsecureSkipVerify: true,
+	})))
+	assert.NoError(t, control(conn, "e2-1"))
+	assert.Equal(t, codes.PermissionDenied, status.Code(control(conn, "e2-2")))
+	assert.Len(t, upstream.e2t.Controls(), 1)
+}
+
+func TestShutdownSharedSubscription(t *testing.T) {
+	upstream := newTestUpstream(t)
+	upstream.start(t)
+	proxy := newTestProxy(t, upstream, func(config *Config) {
+		config.ShutdownTimeout = 10 * time.Second
+		config.MultiplexSubscriptions = true
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	stream := proxy.subscribe(t, ctx)
+	subscribes := proxy.e2t.Subscribes()
+	require.Len(t, subscribes, 1)
+
+	// The shared subscription is deleted once its last subscriber leaves, before the E2T connection is closed
+	proxy.e2t.DelayUnsubscribes(500 * time.Millisecond)
+	stopCh := proxy.stop()
+	_, err := stream.Recv()
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	select {
+	case err := <-stopCh:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("not stopped after the stream was ended")
+	}
+	unsubscribes := proxy.e2t.Unsubscribes()
+	if assert.Len(t, unsubscribes, 1) {
+		assert.Equal(t, subscribes[0].TransactionID, unsubscribes[0].TransactionID)
+	}
+}
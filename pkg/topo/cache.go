@@ -0,0 +1,301 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package topo
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	topoapi "github.com/onosproject/onos-api/go/onos/topo"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/onosproject/onos-lib-go/pkg/logging"
+	"google.golang.org/grpc"
+)
+
+var log = logging.GetLogger()
+
+const (
+	watcherBufferSize = 1000
+	minRetryDelay     = 100 * time.Millisecond
+	maxRetryDelay     = 10 * time.Second
+)
+
+// NewCache creates a new topo cache fed from the topo service reachable via the given connection
+func NewCache(conn *grpc.ClientConn) *Cache {
+	return &Cache{
+		conn:     conn,
+		objects:  make(objectsByID),
+		watchers: make(map[*watcher]bool),
+		syncCh:   make(chan struct{}),
+	}
+}
+
+// Cache is an in-memory replica of the topo objects maintained using a single upstream watch
+type Cache struct {
+	conn     *grpc.ClientConn
+	objects  objectsByID
+	watchers map[*watcher]bool
+	synced   bool
+	syncCh   chan struct{}
+	cancel   context.CancelFunc
+	mu       sync.RWMutex
+}
+
+type watcher struct {
+	filters *topoapi.Filters
+	ch      chan topoapi.Event
+}
+
+// Start starts maintaining the cache in the background
+func (c *Cache) Start() {
+	log.Info("Starting topo cache")
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	go c.run(ctx)
+}
+
+// Close stops maintaining the cache and closes all watchers
+func (c *Cache) Close() {
+	if c.cancel != nil {
+		c.cancel()
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for w := range c.watchers {
+		close(w.ch)
+		delete(c.watchers, w)
+	}
+}
+
+// run keeps the cache synchronized with the topo service, reconnecting whenever the watch fails
+func (c *Cache) run(ctx context.Context) {
+	delay := minRetryDelay
+	for {
+		start := time.Now()
+		err := c.sync(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(start) > maxRetryDelay {
+			delay = minRetryDelay
+		}
+		log.Warnf("Topo watch failed: %v; reconnecting in %s", err, delay)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return
+		}
+		delay *= 2
+		if delay > maxRetryDelay {
+			delay = maxRetryDelay
+		}
+	}
+}
+
+// sync opens a watch, reconciles the cache with the list of present objects and then applies the watch events
+func (c *Cache) sync(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	client := topoapi.NewTopoClient(c.conn)
+	stream, err := OpenWatch(ctx, client)
+	if err != nil {
+		return err
+	}
+	response, err := client.List(ctx, &topoapi.ListRequest{})
+	if err != nil {
+		return err
+	}
+	c.reconcile(response.Objects)
+
+	for {
+		response, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		c.update(response.Event)
+	}
+}
+
+// reconcile replaces the cached objects with the given ones, notifying watchers about any differences
+func (c *Cache) reconcile(objects []topoapi.Object) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	present := make(map[topoapi.ID]bool)
+	for _, object := range objects {
+		present[object.ID] = true
+		if cached, ok := c.objects[object.ID]; !ok {
+			c.apply(topoapi.Event{Type: topoapi.EventType_ADDED, Object: object})
+		} else if object.Revision != cached.Revision {
+			c.apply(topoapi.Event{Type: topoapi.EventType_UPDATED, Object: object})
+		}
+	}
+	for id, cached := range c.objects {
+		if !present[id] {
+			c.apply(topoapi.Event{Type: topoapi.EventType_REMOVED, Object: cached})
+		}
+	}
+
+	if !c.synced {
+		c.synced = true
+		close(c.syncCh)
+	}
+	log.Infof("Synchronized topo cache with %d objects", len(c.objects))
+}
+
+// update applies the given watch event to the cache unless it is older than the cached state
+func (c *Cache) update(event topoapi.Event) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cached, ok := c.objects[event.Object.ID]
+	switch event.Type {
+	case topoapi.EventType_REMOVED:
+		if !ok || cached.Revision > event.Object.Revision {
+			return
+		}
+	default:
+		if ok && cached.Revision >= event.Object.Revision {
+			return
+		}
+		if ok {
+			event.Type = topoapi.EventType_UPDATED
+		} else {
+			event.Type = topoapi.EventType_ADDED
+		}
+	}
+	c.apply(event)
+}
+
+// apply updates the cached objects and notifies the matching watchers; must be called with the lock held
+func (c *Cache) apply(event topoapi.Event) {
+	log.Debugf("Applying topo event %+v", event)
+	removed := event.Type == topoapi.EventType_REMOVED
+	if !removed {
+		c.objects[event.Object.ID] = event.Object
+	}
+
+	// Removed objects are matched against the state prior to their removal
+	var matches []*watcher
+	for w := range c.watchers {
+		if matchesWatch(c.objects, w.filters, event.Object) {
+			matches = append(matches, w)
+		}
+	}
+	if removed {
+		delete(c.objects, event.Object.ID)
+	}
+
+	for _, w := range matches {
+		select {
+		case w.ch <- event:
+		default:
+			log.Warnf("Closing slow topo watcher")
+			close(w.ch)
+			delete(c.watchers, w)
+		}
+	}
+}
+
+// matchesWatch returns whether the given object is selected by the filters within the given set of objects
+func matchesWatch(objects objectsByID, filters *topoapi.Filters, object topoapi.Object) bool {
+	if filters == nil || filters.RelationFilter == nil {
+		return match(object, filters)
+	}
+	for _, related := range filterRelated(objects, filters) {
+		if related.ID == object.ID {
+			return true
+		}
+	}
+	return false
+}
+
+// waitForSync waits until the cache has been synchronized with the topo service at least once
+func (c *Cache) waitForSync(ctx context.Context) error {
+	select {
+	case <-c.syncCh:
+		return nil
+	case <-ctx.Done():
+		return errors.NewUnavailable("topo cache not synchronized: %v", ctx.Err())
+	}
+}
+
+// Live always returns nil since the cache keeps reconnecting to the topo service until it is closed
+func (c *Cache) Live() error {
+	return nil
+}
+
+// Ready returns an error until the cache has been synchronized with the topo service at least once
+func (c *Cache) Ready() error {
+	select {
+	case <-c.syncCh:
+		return nil
+	default:
+		return fmt.Errorf("topo cache has not been synchronized")
+	}
+}
+
+// Get returns the cached object with the given ID
+func (c *Cache) Get(ctx context.Context, id topoapi.ID) (*topoapi.Object, error) {
+	if err := c.waitForSync(ctx); err != nil {
+		return nil, err
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	object, ok := c.objects[id]
+	if !ok {
+		return nil, errors.NewNotFound("object %s not found", id)
+	}
+	return &object, nil
+}
+
+// List returns the cached objects matching the given filters in the requested order
+func (c *Cache) List(ctx context.Context, filters *topoapi.Filters, order topoapi.SortOrder) ([]topoapi.Object, error) {
+	if err := c.waitForSync(ctx); err != nil {
+		return nil, err
+	}
+	c.mu.RLock()
+	objects := filterObjects(c.objects, filters)
+	c.mu.RUnlock()
+	sortObjects(objects, order)
+	return objects, nil
+}
+
+// Watch returns a channel of events for the cached objects matching the given filters. Unless noreplay
+// is set, the channel first yields the matching objects already in the cache as events with no type.
+// The channel is closed when the context is done or when the watcher falls too far behind.
+func (c *Cache) Watch(ctx context.Context, filters *topoapi.Filters, noreplay bool) (<-chan topoapi.Event, error) {
+	if err := c.waitForSync(ctx); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	var replay []topoapi.Object
+	if !noreplay {
+		replay = filterObjects(c.objects, filters)
+	}
+	w := &watcher{
+		filters: filters,
+		ch:      make(chan topoapi.Event, len(replay)+watcherBufferSize),
+	}
+	for _, object := range replay {
+		w.ch <- topoapi.Event{Type: topoapi.EventType_NONE, Object: object}
+	}
+	c.watchers[w] = true
+	c.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.watchers[w] {
+			close(w.ch)
+			delete(c.watchers, w)
+		}
+	}()
+	return w.ch, nil
+}
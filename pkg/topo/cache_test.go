@@ -0,0 +1,186 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package topo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	topoapi "github.com/onosproject/onos-api/go/onos/topo"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/onosproject/onos-lib-go/pkg/grpc/retry"
+	"github.com/onosproject/onos-proxy/pkg/harness"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+func newEntity(id topoapi.ID, kind topoapi.ID, revision topoapi.Revision, labels map[string]string) topoapi.Object {
+	return topoapi.Object{
+		ID:       id,
+		Revision: revision,
+		Type:     topoapi.Object_ENTITY,
+		Obj:      &topoapi.Object_Entity{Entity: &topoapi.Entity{KindID: kind}},
+		Labels:   labels,
+	}
+}
+
+func newRelation(id topoapi.ID, kind topoapi.ID, src topoapi.ID, tgt topoapi.ID) topoapi.Object {
+	return topoapi.Object{
+		ID:       id,
+		Revision: 1,
+		Type:     topoapi.Object_RELATION,
+		Obj: &topoapi.Object_Relation{Relation: &topoapi.Relation{
+			KindID:      kind,
+			SrcEntityID: src,
+			TgtEntityID: tgt,
+		}},
+	}
+}
+
+func TestCacheGetList(t *testing.T) {
+	cache := NewCache(nil)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	cache.reconcile([]topoapi.Object{
+		newEntity("e2t-1", topoapi.E2T, 1, nil),
+		newEntity("e2-1", topoapi.E2NODE, 1, map[string]string{"site": "a"}),
+		newEntity("e2-2", topoapi.E2NODE, 1, map[string]string{"site": "b"}),
+		newRelation("e2t-1-e2-1", topoapi.CONTROLS, "e2t-1", "e2-1"),
+	})
+
+	object, err := cache.Get(ctx, "e2-1")
+	assert.NoError(t, err)
+	assert.Equal(t, topoapi.ID("e2-1"), object.ID)
+
+	_, err = cache.Get(ctx, "e2-3")
+	assert.True(t, errors.IsNotFound(err))
+
+	objects, err := cache.List(ctx, nil, topoapi.SortOrder_ASCENDING)
+	assert.NoError(t, err)
+	assert.Len(t, objects, 4)
+	assert.Equal(t, topoapi.ID("e2-1"), objects[0].ID)
+
+	objects, err = cache.List(ctx, &topoapi.Filters{
+		KindFilter: &topoapi.Filter{Filter: &topoapi.Filter_Equal_{Equal_: &topoapi.EqualFilter{Value: topoapi.E2NODE}}},
+		LabelFilters: []*topoapi.Filter{{
+			Key:    "site",
+			Filter: &topoapi.Filter_Not{Not: &topoapi.NotFilter{Inner: &topoapi.Filter{Filter: &topoapi.Filter_Equal_{Equal_: &topoapi.EqualFilter{Value: "a"}}}}},
+		}},
+	}, topoapi.SortOrder_UNORDERED)
+	assert.NoError(t, err)
+	assert.Len(t, objects, 1)
+	assert.Equal(t, topoapi.ID("e2-2"), objects[0].ID)
+
+	objects, err = cache.List(ctx, &topoapi.Filters{
+		RelationFilter: &topoapi.RelationFilter{SrcId: "e2t-1", RelationKind: topoapi.CONTROLS},
+	}, topoapi.SortOrder_UNORDERED)
+	assert.NoError(t, err)
+	assert.Len(t, objects, 1)
+	assert.Equal(t, topoapi.ID("e2-1"), objects[0].ID)
+
+	objects, err = cache.List(ctx, &topoapi.Filters{
+		RelationFilter: &topoapi.RelationFilter{SrcId: "e2t-1", Scope: topoapi.RelationFilterScope_ALL},
+	}, topoapi.SortOrder_ASCENDING)
+	assert.NoError(t, err)
+	assert.Len(t, objects, 2)
+	assert.Equal(t, topoapi.ID("e2t-1-e2-1"), objects[1].ID)
+}
+
+func TestCacheNotSynchronized(t *testing.T) {
+	cache := NewCache(nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := cache.List(ctx, nil, topoapi.SortOrder_UNORDERED)
+	assert.True(t, errors.IsUnavailable(err))
+	assert.NoError(t, cache.Live())
+	assert.Error(t, cache.Ready())
+
+	cache.reconcile(nil)
+	assert.NoError(t, cache.Ready())
+}
+
+func TestCacheWatch(t *testing.T) {
+	cache := NewCache(nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cache.reconcile([]topoapi.Object{
+		newEntity("e2-1", topoapi.E2NODE, 1, nil),
+		newEntity("e2t-1", topoapi.E2T, 1, nil),
+	})
+
+	ch, err := cache.Watch(ctx, &topoapi.Filters{
+		KindFilter: &topoapi.Filter{Filter: &topoapi.Filter_Equal_{Equal_: &topoapi.EqualFilter{Value: topoapi.E2NODE}}},
+	}, false)
+	assert.NoError(t, err)
+
+	event := <-ch
+	assert.Equal(t, topoapi.EventType_NONE, event.Type)
+	assert.Equal(t, topoapi.ID("e2-1"), event.Object.ID)
+
+	// Stale and non-matching events are not delivered
+	cache.update(topoapi.Event{Type: topoapi.EventType_UPDATED, Object: newEntity("e2-1", topoapi.E2NODE, 1, nil)})
+	cache.update(topoapi.Event{Type: topoapi.EventType_ADDED, Object: newEntity("e2t-2", topoapi.E2T, 1, nil)})
+	cache.update(topoapi.Event{Type: topoapi.EventType_ADDED, Object: newEntity("e2-2", topoapi.E2NODE, 1, nil)})
+	event = <-ch
+	assert.Equal(t, topoapi.EventType_ADDED, event.Type)
+	assert.Equal(t, topoapi.ID("e2-2"), event.Object.ID)
+
+	// Objects missing after a resync are removed
+	cache.reconcile([]topoapi.Object{
+		newEntity("e2-2", topoapi.E2NODE, 2, nil),
+	})
+	received := make(map[topoapi.ID]topoapi.EventType)
+	for i := 0; i < 2; i++ {
+		event = <-ch
+		received[event.Object.ID] = event.Type
+	}
+	assert.Equal(t, topoapi.EventType_UPDATED, received["e2-2"])
+	assert.Equal(t, topoapi.EventType_REMOVED, received["e2-1"])
+
+	cancel()
+	for range ch {
+	}
+}
+
+func TestCacheResync(t *testing.T) {
+	network := harness.NewNetwork()
+	defer network.Close()
+	topoServer := harness.NewTopoServer()
+	network.Serve("onos-topo:5150", topoServer.Register)
+	topoServer.AddE2T("e2t-1", "10.0.0.1", 5150)
+
+	// The connection is configured with the same retrying interceptors as the manager's
+	conn, err := grpc.Dial("onos-topo:5150", append(network.DialOptions(),
+		grpc.WithUnaryInterceptor(retry.RetryingUnaryClientInterceptor(retry.WithRetryOn(codes.Unavailable))),
+		grpc.WithStreamInterceptor(retry.RetryingStreamClientInterceptor(retry.WithRetryOn(codes.Unavailable))))...)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	cache := NewCache(conn)
+	cache.Start()
+	defer cache.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	object, err := cache.Get(ctx, "e2t-1")
+	assert.NoError(t, err)
+	assert.Equal(t, topoapi.ID("e2t-1"), object.ID)
+
+	// Changes made while the watch is broken are picked up on resynchronization
+	assert.Eventually(t, func() bool {
+		return topoServer.Watches() == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	topoServer.BreakWatches()
+	topoServer.Remove("e2t-1")
+	topoServer.AddE2T("e2t-2", "10.0.0.2", 5150)
+	assert.Eventually(t, func() bool {
+		objects, err := cache.List(ctx, nil, topoapi.SortOrder_ASCENDING)
+		return err == nil && len(objects) == 1 && objects[0].ID == "e2t-2"
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,155 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package topo
+
+import (
+	"sort"
+
+	topoapi "github.com/onosproject/onos-api/go/onos/topo"
+)
+
+// objectsByID is the set of cached objects indexed by their ID
+type objectsByID map[topoapi.ID]topoapi.Object
+
+// filterObjects returns the objects matching the given filters
+func filterObjects(objects objectsByID, filters *topoapi.Filters) []topoapi.Object {
+	if filters != nil && filters.RelationFilter != nil {
+		return filterRelated(objects, filters)
+	}
+	matches := make([]topoapi.Object, 0, len(objects))
+	for _, object := range objects {
+		if match(object, filters) {
+			matches = append(matches, object)
+		}
+	}
+	return matches
+}
+
+// filterRelated returns the objects related to the source entity of the relation filter and matching the filters
+func filterRelated(objects objectsByID, filters *topoapi.Filters) []topoapi.Object {
+	relationFilter := filters.RelationFilter
+	var matches []topoapi.Object
+	included := make(map[topoapi.ID]bool)
+	include := func(object topoapi.Object) {
+		if !included[object.ID] {
+			included[object.ID] = true
+			matches = append(matches, object)
+		}
+	}
+
+	for _, object := range objects {
+		relation := object.GetRelation()
+		if relation == nil || string(relation.SrcEntityID) != relationFilter.SrcId {
+			continue
+		}
+		if relationFilter.RelationKind != "" && string(relation.KindID) != relationFilter.RelationKind {
+			continue
+		}
+		if relationFilter.TargetId != "" && string(relation.TgtEntityID) != relationFilter.TargetId {
+			continue
+		}
+		target, ok := objects[relation.TgtEntityID]
+		if !ok {
+			continue
+		}
+		if relationFilter.TargetKind != "" && string(kindOf(target)) != relationFilter.TargetKind {
+			continue
+		}
+		if !match(target, filters) {
+			continue
+		}
+
+		switch relationFilter.Scope {
+		case topoapi.RelationFilterScope_ALL:
+			include(object)
+			include(target)
+		case topoapi.RelationFilterScope_SOURCE_AND_TARGET:
+			if source, ok := objects[relation.SrcEntityID]; ok {
+				include(source)
+			}
+			include(target)
+		default:
+			include(target)
+		}
+	}
+	return matches
+}
+
+// match returns whether the given object satisfies the kind, label, type and aspect filters
+func match(object topoapi.Object, filters *topoapi.Filters) bool {
+	if filters == nil {
+		return true
+	}
+	if len(filters.ObjectTypes) > 0 {
+		found := false
+		for _, objectType := range filters.ObjectTypes {
+			if object.Type == objectType {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if filters.KindFilter != nil && !matchFilter(filters.KindFilter, string(kindOf(object))) {
+		return false
+	}
+	for _, labelFilter := range filters.LabelFilters {
+		if !matchFilter(labelFilter, object.Labels[labelFilter.Key]) {
+			return false
+		}
+	}
+	for _, aspect := range filters.WithAspects {
+		if _, ok := object.Aspects[aspect]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func matchFilter(filter *topoapi.Filter, value string) bool {
+	switch f := filter.Filter.(type) {
+	case *topoapi.Filter_Equal_:
+		return f.Equal_.Value == value
+	case *topoapi.Filter_In:
+		for _, v := range f.In.Values {
+			if v == value {
+				return true
+			}
+		}
+		return false
+	case *topoapi.Filter_Not:
+		return f.Not.Inner == nil || !matchFilter(f.Not.Inner, value)
+	}
+	return true
+}
+
+// kindOf returns the kind of the given entity or relation object
+func kindOf(object topoapi.Object) topoapi.ID {
+	switch obj := object.Obj.(type) {
+	case *topoapi.Object_Entity:
+		return obj.Entity.KindID
+	case *topoapi.Object_Relation:
+		return obj.Relation.KindID
+	case *topoapi.Object_Kind:
+		return object.ID
+	}
+	return ""
+}
+
+// sortObjects sorts the given objects by their ID according to the requested order
+func sortObjects(objects []topoapi.Object, order topoapi.SortOrder) {
+	switch order {
+	case topoapi.SortOrder_ASCENDING:
+		sort.Slice(objects, func(i, j int) bool {
+			return objects[i].ID < objects[j].ID
+		})
+	case topoapi.SortOrder_DESCENDING:
+		sort.Slice(objects, func(i, j int) bool {
+			return objects[i].ID > objects[j].ID
+		})
+	}
+}
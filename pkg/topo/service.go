@@ -0,0 +1,129 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package topo
+
+import (
+	"context"
+
+	topoapi "github.com/onosproject/onos-api/go/onos/topo"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/onosproject/onos-lib-go/pkg/northbound"
+	"google.golang.org/grpc"
+)
+
+// NewProxyService creates a new topo proxy service serving reads from the given cache
+func NewProxyService(clientConn *grpc.ClientConn, cache *Cache) northbound.Service {
+	return &Service{
+		conn:  clientConn,
+		cache: cache,
+	}
+}
+
+// Service is a Service implementation for the topo service.
+type Service struct {
+	northbound.Service
+	conn  *grpc.ClientConn
+	cache *Cache
+}
+
+// Register registers the Service with the gRPC server.
+func (s Service) Register(r *grpc.Server) {
+	server := &ProxyServer{
+		conn:  s.conn,
+		cache: s.cache,
+	}
+	topoapi.RegisterTopoServer(r, server)
+}
+
+// ProxyServer implements the topo gRPC service, serving reads from the local cache and forwarding writes
+type ProxyServer struct {
+	conn  *grpc.ClientConn
+	cache *Cache
+}
+
+func (s *ProxyServer) Create(ctx context.Context, request *topoapi.CreateRequest) (*topoapi.CreateResponse, error) {
+	log.Debugf("CreateRequest %+v", request)
+	response, err := topoapi.NewTopoClient(s.conn).Create(ctx, request)
+	if err != nil {
+		log.Warnf("CreateRequest %+v error: %s", request, err)
+		return nil, err
+	}
+	log.Debugf("CreateResponse %+v", response)
+	return response, nil
+}
+
+func (s *ProxyServer) Get(ctx context.Context, request *topoapi.GetRequest) (*topoapi.GetResponse, error) {
+	log.Debugf("GetRequest %+v", request)
+	object, err := s.cache.Get(ctx, request.ID)
+	if err != nil {
+		log.Debugf("GetRequest %+v error: %s", request, err)
+		return nil, errors.Status(err).Err()
+	}
+	response := &topoapi.GetResponse{
+		Object: object,
+	}
+	log.Debugf("GetResponse %+v", response)
+	return response, nil
+}
+
+func (s *ProxyServer) Update(ctx context.Context, request *topoapi.UpdateRequest) (*topoapi.UpdateResponse, error) {
+	log.Debugf("UpdateRequest %+v", request)
+	response, err := topoapi.NewTopoClient(s.conn).Update(ctx, request)
+	if err != nil {
+		log.Warnf("UpdateRequest %+v error: %s", request, err)
+		return nil, err
+	}
+	log.Debugf("UpdateResponse %+v", response)
+	return response, nil
+}
+
+func (s *ProxyServer) Delete(ctx context.Context, request *topoapi.DeleteRequest) (*topoapi.DeleteResponse, error) {
+	log.Debugf("DeleteRequest %+v", request)
+	response, err := topoapi.NewTopoClient(s.conn).Delete(ctx, request)
+	if err != nil {
+		log.Warnf("DeleteRequest %+v error: %s", request, err)
+		return nil, err
+	}
+	log.Debugf("DeleteResponse %+v", response)
+	return response, nil
+}
+
+func (s *ProxyServer) List(ctx context.Context, request *topoapi.ListRequest) (*topoapi.ListResponse, error) {
+	log.Debugf("ListRequest %+v", request)
+	objects, err := s.cache.List(ctx, request.Filters, request.SortOrder)
+	if err != nil {
+		log.Warnf("ListRequest %+v error: %s", request, err)
+		return nil, errors.Status(err).Err()
+	}
+	response := &topoapi.ListResponse{
+		Objects: objects,
+	}
+	log.Debugf("ListResponse %+v", response)
+	return response, nil
+}
+
+func (s *ProxyServer) Watch(request *topoapi.WatchRequest, server topoapi.Topo_WatchServer) error {
+	log.Debugf("WatchRequest %+v", request)
+	ch, err := s.cache.Watch(server.Context(), request.Filters, request.Noreplay)
+	if err != nil {
+		log.Warnf("WatchRequest %+v error: %s", request, err)
+		return errors.Status(err).Err()
+	}
+
+	for event := range ch {
+		response := &topoapi.WatchResponse{
+			Event: event,
+		}
+		log.Debugf("WatchResponse %+v", response)
+		if err := server.Send(response); err != nil {
+			log.Warnf("WatchResponse %+v error: %s", response, err)
+			return err
+		}
+	}
+	if err := server.Context().Err(); err != nil {
+		return err
+	}
+	return errors.Status(errors.NewUnavailable("topo watch closed")).Err()
+}
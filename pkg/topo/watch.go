@@ -0,0 +1,20 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package topo
+
+import (
+	"context"
+
+	topoapi "github.com/onosproject/onos-api/go/onos/topo"
+	"github.com/onosproject/onos-lib-go/pkg/grpc/retry"
+)
+
+// OpenWatch opens a watch of the changes to the topo objects from now on, for keeping a local copy of the topo
+// state up to date. Retries are disabled for the watch, even on connections with retrying interceptors, since a
+// watch transparently reopened after breaking would miss the events emitted meanwhile; callers instead handle
+// failures by resynchronizing their copy, i.e. opening a new watch and listing the present objects.
+func OpenWatch(ctx context.Context, client topoapi.TopoClient) (topoapi.Topo_WatchClient, error) {
+	return client.Watch(ctx, &topoapi.WatchRequest{Noreplay: true}, retry.WithRetryOn())
+}
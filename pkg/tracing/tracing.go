@@ -0,0 +1,75 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tracing
+
+import (
+	"context"
+
+	"github.com/onosproject/onos-lib-go/pkg/logging"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"google.golang.org/grpc"
+)
+
+var log = logging.GetLogger()
+
+const serviceName = "onos-proxy"
+
+// Span attributes describing how a proxied request was routed
+const (
+	// E2NodeIDKey is the ID of the E2 node targeted by the request
+	E2NodeIDKey = attribute.Key("onos.e2.node_id")
+	// E2TAddressKey is the address of the E2T instance the request was forwarded to
+	E2TAddressKey = attribute.Key("onos.e2t.address")
+	// MastershipTermKey is the mastership term of the E2T instance for the targeted E2 node
+	MastershipTermKey = attribute.Key("onos.e2.mastership_term")
+)
+
+// Init installs the global tracer provider exporting spans via OTLP to the collector at the given endpoint,
+// and the W3C trace context propagator. If the endpoint is empty, spans are not recorded but the trace
+// context is still propagated. The returned function flushes and stops the exporter.
+func Init(ctx context.Context, endpoint string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracegrpc.New(ctx,
+		otlptracegrpc.WithEndpoint(endpoint),
+		otlptracegrpc.WithInsecure())
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(serviceName))))
+	otel.SetTracerProvider(provider)
+	log.Infof("Exporting traces to %s", endpoint)
+	return provider.Shutdown, nil
+}
+
+// ServerOptions returns the gRPC server options extracting the incoming trace context and creating a span for each call
+func ServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(otelgrpc.UnaryServerInterceptor()),
+		grpc.ChainStreamInterceptor(otelgrpc.StreamServerInterceptor()),
+	}
+}
+
+// UnaryClientInterceptor returns a client interceptor creating a span for each call and propagating its context upstream
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return otelgrpc.UnaryClientInterceptor()
+}
+
+// StreamClientInterceptor returns a client interceptor creating a span for each stream and propagating its context upstream
+func StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return otelgrpc.StreamClientInterceptor()
+}
@@ -0,0 +1,94 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// testCollector is an in-process OTLP trace collector
+type testCollector struct {
+	collectortrace.UnimplementedTraceServiceServer
+	spans []*tracepb.Span
+	mu    sync.Mutex
+}
+
+func (c *testCollector) Export(ctx context.Context, request *collectortrace.ExportTraceServiceRequest) (*collectortrace.ExportTraceServiceResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, resourceSpans := range request.ResourceSpans {
+		for _, scopeSpans := range resourceSpans.ScopeSpans {
+			c.spans = append(c.spans, scopeSpans.Spans...)
+		}
+		for _, libSpans := range resourceSpans.InstrumentationLibrarySpans {
+			c.spans = append(c.spans, libSpans.Spans...)
+		}
+	}
+	return &collectortrace.ExportTraceServiceResponse{}, nil
+}
+
+func serve(t *testing.T, server *grpc.Server) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go func() {
+		_ = server.Serve(lis)
+	}()
+	t.Cleanup(server.Stop)
+	return lis.Addr().String()
+}
+
+func TestTracePropagation(t *testing.T) {
+	collector := &testCollector{}
+	collectorServer := grpc.NewServer()
+	collectortrace.RegisterTraceServiceServer(collectorServer, collector)
+	collectorAddress := serve(t, collectorServer)
+
+	shutdown, err := Init(context.Background(), collectorAddress)
+	assert.NoError(t, err)
+
+	server := grpc.NewServer(ServerOptions()...)
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	address := serve(t, server)
+
+	conn, err := grpc.Dial(address,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(UnaryClientInterceptor()))
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	_, err = healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	assert.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+	assert.Len(t, collector.spans, 2)
+	var clientSpan, serverSpan *tracepb.Span
+	for _, span := range collector.spans {
+		switch span.Kind {
+		case tracepb.Span_SPAN_KIND_CLIENT:
+			clientSpan = span
+		case tracepb.Span_SPAN_KIND_SERVER:
+			serverSpan = span
+		}
+	}
+	if assert.NotNil(t, clientSpan) && assert.NotNil(t, serverSpan) {
+		// The server span continues the trace propagated by the client
+		assert.True(t, bytes.Equal(clientSpan.TraceId, serverSpan.TraceId))
+		assert.True(t, bytes.Equal(clientSpan.SpanId, serverSpan.ParentSpanId))
+	}
+}
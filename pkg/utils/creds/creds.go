@@ -6,10 +6,12 @@ package creds
 
 import (
 	"crypto/tls"
+
 	"github.com/onosproject/onos-lib-go/pkg/certs"
 )
 
-// GetClientCredentials :
+// GetClientCredentials returns the TLS configuration presenting the built-in default client certificate
+// without verifying the server certificate. It is insecure and only meant for development.
 func GetClientCredentials() (*tls.Config, error) {
 	cert, err := tls.X509KeyPair([]byte(certs.DefaultClientCrt), []byte(certs.DefaultClientKey))
 	if err != nil {
@@ -0,0 +1,103 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package creds
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/onosproject/onos-proxy/pkg/harness"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestClientConfig(t *testing.T) {
+	dir := t.TempDir()
+	certs, err := harness.NewCertificates(dir, "onos-e2t")
+	require.NoError(t, err)
+	serverCreds, err := certs.ServerCredentials()
+	require.NoError(t, err)
+
+	network := harness.NewNetwork()
+	defer network.Close()
+	network.Serve("onos-e2t:5150", func(server *grpc.Server) {
+		healthpb.RegisterHealthServer(server, health.NewServer())
+	}, grpc.Creds(serverCreds))
+
+	check := func(address string, creds credentials.TransportCredentials) error {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		conn, err := grpc.DialContext(ctx, address,
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+				return network.Dial(ctx, "onos-e2t:5150")
+			}),
+			grpc.WithTransportCredentials(creds))
+		require.NoError(t, err)
+		defer conn.Close()
+		_, err = healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+		return err
+	}
+
+	reloader, err := NewReloader(certs.CAPath, certs.ClientCertPath, certs.ClientKeyPath)
+	require.NoError(t, err)
+	tlsConfig := reloader.ClientConfig()
+	assert.NoError(t, check("onos-e2t:5150", credentials.NewTLS(tlsConfig)))
+
+	// The server name is verified
+	assert.Error(t, check("onos-topo:5150", credentials.NewTLS(tlsConfig)))
+
+	// The server requires a client certificate issued by its CA
+	otherCerts, err := harness.NewCertificates(t.TempDir(), "onos-e2t")
+	require.NoError(t, err)
+	otherReloader, err := NewReloader(certs.CAPath, otherCerts.ClientCertPath, otherCerts.ClientKeyPath)
+	require.NoError(t, err)
+	assert.Error(t, check("onos-e2t:5150", credentials.NewTLS(otherReloader.ClientConfig())))
+
+	// The server certificate must be issued by the configured CA
+	insecureConfig, err := GetClientCredentials()
+	require.NoError(t, err)
+	assert.Error(t, check("onos-e2t:5150", credentials.NewTLS(insecureConfig)))
+	otherCAReloader, err := NewReloader(otherCerts.CAPath, certs.ClientCertPath, certs.ClientKeyPath)
+	require.NoError(t, err)
+	assert.Error(t, check("onos-e2t:5150", credentials.NewTLS(otherCAReloader.ClientConfig())))
+
+	// A server certificate cannot be verified without a server name
+	serverCert, err := tls.LoadX509KeyPair(certs.ServerCertPath, certs.ServerKeyPath)
+	require.NoError(t, err)
+	peerCert, err := x509.ParseCertificate(serverCert.Certificate[0])
+	require.NoError(t, err)
+	state := tls.ConnectionState{ServerName: "onos-e2t", PeerCertificates: []*x509.Certificate{peerCert}}
+	assert.NoError(t, tlsConfig.VerifyConnection(state))
+	state.ServerName = ""
+	assert.Error(t, tlsConfig.VerifyConnection(state))
+}
+
+func TestReloaderErrors(t *testing.T) {
+	dir := t.TempDir()
+	certs, err := harness.NewCertificates(dir, "onos-e2t")
+	require.NoError(t, err)
+
+	_, err = NewReloader(certs.CAPath, certs.ClientCertPath, filepath.Join(dir, "missing.key"))
+	assert.Error(t, err)
+	_, err = NewReloader(certs.CAPath, certs.ClientCertPath, certs.ServerKeyPath)
+	assert.Error(t, err)
+	_, err = NewReloader(filepath.Join(dir, "missing.crt"), certs.ClientCertPath, certs.ClientKeyPath)
+	assert.Error(t, err)
+
+	emptyCA := filepath.Join(dir, "empty.crt")
+	require.NoError(t, os.WriteFile(emptyCA, []byte("not a certificate"), 0600))
+	_, err = NewReloader(emptyCA, certs.ClientCertPath, certs.ClientKeyPath)
+	assert.Error(t, err)
+}
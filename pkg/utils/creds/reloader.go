@@ -0,0 +1,184 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package creds
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/onosproject/onos-lib-go/pkg/logging"
+)
+
+var log = logging.GetLogger()
+
+// DefaultReloadInterval is the default interval at which the certificate files are checked for changes
+const DefaultReloadInterval = 10 * time.Second
+
+// NewReloader creates a new reloader serving the certificate, key and CA certificates in the given PEM files.
+// The files are loaded immediately and an error is returned if they cannot be.
+func NewReloader(caPath, certPath, keyPath string) (*Reloader, error) {
+	r := &Reloader{
+		caPath:   caPath,
+		certPath: certPath,
+		keyPath:  keyPath,
+		done:     make(chan struct{}),
+	}
+	if _, err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reloader keeps a certificate and CA loaded from PEM files, reloading them whenever the files change, e.g.
+// when rotated by cert-manager. The TLS configurations it returns pick up reloaded files on each new handshake
+// while established connections are left intact.
+type Reloader struct {
+	caPath   string
+	certPath string
+	keyPath  string
+	files    [][]byte
+	cert     *tls.Certificate
+	certPool *x509.CertPool
+	done     chan struct{}
+	stop     sync.Once
+	mu       sync.RWMutex
+}
+
+// Start checks the files for changes at the given interval until the reloader is closed
+func (r *Reloader) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := r.Reload(); err != nil {
+					// The files may be mid-rotation; the previous certificates remain in use until the next attempt
+					log.Warnf("Unable to reload TLS certificates: %v", err)
+				}
+			case <-r.done:
+				return
+			}
+		}
+	}()
+}
+
+// Close stops checking the files for changes
+func (r *Reloader) Close() {
+	r.stop.Do(func() {
+		close(r.done)
+	})
+}
+
+// Reload loads the files if their contents changed since they were last loaded, returning whether they did
+func (r *Reloader) Reload() (bool, error) {
+	var files [][]byte
+	for _, path := range []string{r.caPath, r.certPath, r.keyPath} {
+		file, err := os.ReadFile(path)
+		if err != nil {
+			return false, err
+		}
+		files = append(files, file)
+	}
+
+	r.mu.RLock()
+	changed := r.files == nil
+	for i := range r.files {
+		if !bytes.Equal(r.files[i], files[i]) {
+			changed = true
+		}
+	}
+	r.mu.RUnlock()
+	if !changed {
+		return false, nil
+	}
+
+	certPool := x509.NewCertPool()
+	if !certPool.AppendCertsFromPEM(files[0]) {
+		return false, fmt.Errorf("no PEM encoded certificates found in CA certificate %s", r.caPath)
+	}
+	cert, err := tls.X509KeyPair(files[1], files[2])
+	if err != nil {
+		return false, fmt.Errorf("unable to load certificate %s and key %s: %v", r.certPath, r.keyPath, err)
+	}
+
+	r.mu.Lock()
+	reloaded := r.files != nil
+	r.files = files
+	r.cert = &cert
+	r.certPool = certPool
+	r.mu.Unlock()
+	if reloaded {
+		log.Infof("Reloaded TLS certificate %s and CA %s", r.certPath, r.caPath)
+	}
+	return true, nil
+}
+
+// current returns the certificate and CA certificates presently loaded
+func (r *Reloader) current() (*tls.Certificate, *x509.CertPool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, r.certPool
+}
+
+// ClientConfig returns a client TLS configuration presenting the present certificate and verifying server
+// certificates and names against the present CA certificates
+func (r *Reloader) ClientConfig() *tls.Config {
+	return &tls.Config{
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, _ := r.current()
+			return cert, nil
+		},
+		// The standard verification is replaced by an equivalent one against the CA certificates loaded at the
+		// time of the handshake, since the RootCAs of a configuration cannot be replaced once it is in use
+		InsecureSkipVerify: true,
+		VerifyConnection: func(state tls.ConnectionState) error {
+			if len(state.PeerCertificates) == 0 {
+				return fmt.Errorf("no server certificate presented")
+			}
+			// An empty name would skip the verification of the server name
+			if state.ServerName == "" {
+				return fmt.Errorf("no server name to verify the server certificate against")
+			}
+			_, certPool := r.current()
+			opts := x509.VerifyOptions{
+				DNSName:       state.ServerName,
+				Roots:         certPool,
+				Intermediates: x509.NewCertPool(),
+			}
+			for _, cert := range state.PeerCertificates[1:] {
+				opts.Intermediates.AddCert(cert)
+			}
+			_, err := state.PeerCertificates[0].Verify(opts)
+			return err
+		},
+		MinVersion: tls.VersionTLS12,
+	}
+}
+
+// ServerConfig returns a server TLS configuration presenting the present certificate and verifying client
+// certificates against the present CA certificates according to the given policy
+func (r *Reloader) ServerConfig(clientAuth tls.ClientAuthType) *tls.Config {
+	return &tls.Config{
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			cert, certPool := r.current()
+			return &tls.Config{
+				GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+					return cert, nil
+				},
+				ClientCAs:  certPool,
+				ClientAuth: clientAuth,
+				NextProtos: []string{"h2"},
+				MinVersion: tls.VersionTLS12,
+			}, nil
+		},
+		MinVersion: tls.VersionTLS12,
+	}
+}
@@ -0,0 +1,112 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package creds
+
+import (
+	"context"
+	"crypto/tls"
+	"os"
+	"testing"
+	"time"
+
+	e2api "github.com/onosproject/onos-api/go/onos/e2t/e2/v1beta1"
+	"github.com/onosproject/onos-proxy/pkg/harness"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+func TestReloader(t *testing.T) {
+	dir := t.TempDir()
+	certs, err := harness.NewCertificates(dir, "onos-e2t")
+	require.NoError(t, err)
+	serverReloader, err := NewReloader(certs.CAPath, certs.ServerCertPath, certs.ServerKeyPath)
+	require.NoError(t, err)
+	clientReloader, err := NewReloader(certs.CAPath, certs.ClientCertPath, certs.ClientKeyPath)
+	require.NoError(t, err)
+	// A reloader which is not reloaded keeps the original certificates
+	oldReloader, err := NewReloader(certs.CAPath, certs.ClientCertPath, certs.ClientKeyPath)
+	require.NoError(t, err)
+
+	network := harness.NewNetwork()
+	defer network.Close()
+	e2t := harness.NewE2TServer("onos-e2t:5150")
+	network.Serve(e2t.Address(), e2t.Register,
+		grpc.Creds(credentials.NewTLS(serverReloader.ServerConfig(tls.RequireAndVerifyClientCert))))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	dial := func(config *tls.Config) *grpc.ClientConn {
+		conn, err := grpc.DialContext(ctx, e2t.Address(),
+			grpc.WithContextDialer(network.Dial),
+			grpc.WithTransportCredentials(credentials.NewTLS(config)))
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			_ = conn.Close()
+		})
+		return conn
+	}
+	control := func(config *tls.Config) error {
+		_, err := e2api.NewControlServiceClient(dial(config)).Control(ctx, &e2api.ControlRequest{})
+		return err
+	}
+
+	request := &e2api.SubscribeRequest{Headers: e2api.RequestHeaders{E2NodeID: "e2-1"}}
+	stream, err := e2api.NewSubscriptionServiceClient(dial(clientReloader.ClientConfig())).Subscribe(ctx, request)
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.NoError(t, err)
+
+	// Unchanged files are not reloaded
+	reloaded, err := serverReloader.Reload()
+	assert.NoError(t, err)
+	assert.False(t, reloaded)
+
+	// Rotate the CA and both certificates
+	_, err = harness.NewCertificates(dir, "onos-e2t")
+	require.NoError(t, err)
+	reloaded, err = serverReloader.Reload()
+	assert.NoError(t, err)
+	assert.True(t, reloaded)
+	reloaded, err = clientReloader.Reload()
+	assert.NoError(t, err)
+	assert.True(t, reloaded)
+
+	// New connections use the new certificates while the established subscription stream is kept
+	assert.NoError(t, control(clientReloader.ClientConfig()))
+	assert.Error(t, control(oldReloader.ClientConfig()))
+	assert.Equal(t, 1, e2t.Indicate("e2-1", e2api.Indication{Payload: []byte("1")}))
+	response, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("1"), response.GetIndication().Payload)
+
+	// Invalid files are not loaded and the previous certificates remain in use
+	require.NoError(t, os.WriteFile(certs.ClientCertPath, []byte("not a certificate"), 0600))
+	_, err = clientReloader.Reload()
+	assert.Error(t, err)
+	assert.NoError(t, control(clientReloader.ClientConfig()))
+}
+
+func TestReloaderStart(t *testing.T) {
+	dir := t.TempDir()
+	certs, err := harness.NewCertificates(dir, "onos-e2t")
+	require.NoError(t, err)
+	reloader, err := NewReloader(certs.CAPath, certs.ClientCertPath, certs.ClientKeyPath)
+	require.NoError(t, err)
+	oldCert, _ := reloader.current()
+
+	reloader.Start(10 * time.Millisecond)
+	defer reloader.Close()
+	_, err = harness.NewCertificates(dir, "onos-e2t")
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		cert, _ := reloader.current()
+		return cert != oldCert
+	}, 5*time.Second, 10*time.Millisecond)
+
+	_, err = NewReloader(certs.CAPath, certs.ClientCertPath, certs.ServerKeyPath)
+	assert.Error(t, err)
+}